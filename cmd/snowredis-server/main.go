@@ -0,0 +1,246 @@
+// Command snowredis-server serves snowflake IDs over HTTP and the Redis protocol (RESP).
+//
+// Every flag can also be set through the environment variable shown in its usage text;
+// a malformed value stops the server instead of falling back to the default.
+// Without -redis-addr the server runs locally with the configured or default IDs; with it,
+// the worker slot is leased from Redis and released again on SIGTERM or SIGINT.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+	"github.com/sunquakes/snowredis/server"
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// shutdownTimeout is how long in-flight requests may take after a termination signal
+const shutdownTimeout = 10 * time.Second
+
+// config Command line configuration of the server
+type config struct {
+	listen         string
+	respListen     string
+	redisAddr      string
+	redisPassword  string
+	redisDB        int
+	keyPrefix      string
+	datacenterID   int64
+	workerID       int64
+	epoch          int64
+	datacenterBits uint
+	workerBits     uint
+	sequenceBits   uint
+	strict         bool
+	leaseTTL       time.Duration
+	fencing        bool
+	minLifetime    time.Duration
+}
+
+func main() {
+	cfg, err := parseFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("snowredis-server: %v", err)
+	}
+	if err := run(cfg); err != nil {
+		log.Fatalf("snowredis-server: %v", err)
+	}
+}
+
+// run builds the generator and serves HTTP and RESP until a termination signal arrives
+// @param cfg - *config parsed from flags and environment
+// @return error - any error that occurred while starting or serving
+func run(cfg *config) error {
+	if cfg.listen == "" && cfg.respListen == "" {
+		return errors.New("at least one of -listen and -resp-listen is required")
+	}
+	sf, err := buildGenerator(cfg)
+	if err != nil {
+		return err
+	}
+	// Releases the worker slot lease once the servers have drained
+	defer sf.Cleanup()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 2)
+	var httpServer *http.Server
+	if cfg.listen != "" {
+		httpServer = &http.Server{
+			Addr:              cfg.listen,
+			Handler:           server.NewHTTPHandler(sf),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			log.Printf("snowredis-server: HTTP listening on %s", cfg.listen)
+			if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+	}
+	var respServer *server.RESPServer
+	if cfg.respListen != "" {
+		respServer = server.NewRESPServer(sf)
+		go func() {
+			log.Printf("snowredis-server: RESP listening on %s", cfg.respListen)
+			if err := respServer.ListenAndServe(cfg.respListen); !errors.Is(err, server.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+	}
+
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+	}
+
+	if respServer != nil {
+		_ = respServer.Close()
+	}
+	if httpServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil && err == nil {
+			err = fmt.Errorf("shutdown: %w", shutdownErr)
+		}
+	}
+	return err
+}
+
+// buildGenerator creates the snowflake generator described by the configuration
+// @param cfg - *config parsed from flags and environment
+// @return *snowflake.RedisSnowflake - the generator
+// @return error - any error that occurred while connecting to Redis or building
+func buildGenerator(cfg *config) (*snowflake.RedisSnowflake, error) {
+	layout := snowflake.Layout{
+		Epoch:          cfg.epoch,
+		DatacenterBits: cfg.datacenterBits,
+		WorkerBits:     cfg.workerBits,
+		SequenceBits:   cfg.sequenceBits,
+	}
+	builder := snowflake.NewBuilder().
+		SetLayout(layout).
+		SetDatacenterID(cfg.datacenterID).
+		SetWorkerID(cfg.workerID).
+		SetStrictMode(cfg.strict).
+		SetMinLifetime(cfg.minLifetime).
+		SetFencing(cfg.fencing)
+
+	if cfg.redisAddr != "" {
+		client, err := redis.NewClient(&redis.Config{Addr: cfg.redisAddr, Pwd: cfg.redisPassword, Db: cfg.redisDB})
+		if err != nil {
+			return nil, err
+		}
+		builder.SetRedisClient(client).SetKeyPrefix(cfg.keyPrefix).SetLeaseTTL(cfg.leaseTTL)
+	}
+	return builder.Build()
+}
+
+// parseFlags parses the command line, using environment variables as defaults
+// @param fs - *flag.FlagSet to register the flags on
+// @param args - []string command line arguments without the program name
+// @return *config - the parsed configuration
+// @return error - malformed environment variables or flags
+func parseFlags(fs *flag.FlagSet, args []string) (*config, error) {
+	cfg := &config{}
+	e := &env{}
+	fs.StringVar(&cfg.listen, "listen", e.String("SNOWREDIS_LISTEN", ":8080"), "HTTP listen address, empty to disable (SNOWREDIS_LISTEN)")
+	fs.StringVar(&cfg.respListen, "resp-listen", e.String("SNOWREDIS_RESP_LISTEN", ":6390"), "RESP listen address, empty to disable (SNOWREDIS_RESP_LISTEN)")
+	fs.StringVar(&cfg.redisAddr, "redis-addr", e.String("SNOWREDIS_REDIS_ADDR", ""), "Redis address, empty for local mode (SNOWREDIS_REDIS_ADDR)")
+	fs.StringVar(&cfg.redisPassword, "redis-password", e.String("SNOWREDIS_REDIS_PASSWORD", ""), "Redis password (SNOWREDIS_REDIS_PASSWORD)")
+	fs.IntVar(&cfg.redisDB, "redis-db", int(e.Int("SNOWREDIS_REDIS_DB", 0)), "Redis database (SNOWREDIS_REDIS_DB)")
+	fs.StringVar(&cfg.keyPrefix, "key-prefix", e.String("SNOWREDIS_KEY_PREFIX", snowflake.DefaultKeyPrefix), "prefix of the Redis keys (SNOWREDIS_KEY_PREFIX)")
+	fs.Int64Var(&cfg.datacenterID, "datacenter-id", e.Int(snowflake.DefaultDatacenterEnv, 0), "datacenter ID, 0 to allocate ("+snowflake.DefaultDatacenterEnv+")")
+	fs.Int64Var(&cfg.workerID, "worker-id", e.Int(snowflake.DefaultWorkerEnv, 0), "worker ID, 0 to allocate ("+snowflake.DefaultWorkerEnv+")")
+	fs.Int64Var(&cfg.epoch, "epoch", e.Int("SNOWREDIS_EPOCH", snowflake.Epoch), "epoch in milliseconds since the Unix epoch (SNOWREDIS_EPOCH)")
+	fs.UintVar(&cfg.datacenterBits, "datacenter-bits", uint(e.Int("SNOWREDIS_DATACENTER_BITS", int64(snowflake.DefaultLayout.DatacenterBits))), "datacenter ID bits (SNOWREDIS_DATACENTER_BITS)")
+	fs.UintVar(&cfg.workerBits, "worker-bits", uint(e.Int("SNOWREDIS_WORKER_BITS", int64(snowflake.DefaultLayout.WorkerBits))), "worker ID bits (SNOWREDIS_WORKER_BITS)")
+	fs.UintVar(&cfg.sequenceBits, "sequence-bits", uint(e.Int("SNOWREDIS_SEQUENCE_BITS", int64(snowflake.DefaultLayout.SequenceBits))), "sequence bits (SNOWREDIS_SEQUENCE_BITS)")
+	fs.BoolVar(&cfg.strict, "strict", e.Bool("SNOWREDIS_STRICT", false), "enable strict mode (SNOWREDIS_STRICT)")
+	fs.DurationVar(&cfg.leaseTTL, "lease-ttl", e.Duration("SNOWREDIS_LEASE_TTL", 30*time.Second), "worker slot lease TTL when using Redis (SNOWREDIS_LEASE_TTL)")
+	fs.BoolVar(&cfg.fencing, "fencing", e.Bool("SNOWREDIS_FENCING", false), "refuse IDs while the lease is lost and lease a slot again (SNOWREDIS_FENCING)")
+	fs.DurationVar(&cfg.minLifetime, "min-lifetime", e.Duration("SNOWREDIS_MIN_LIFETIME", 365*24*time.Hour), "refuse to start if the layout runs out of timestamps sooner, 0 to disable (SNOWREDIS_MIN_LIFETIME)")
+	if err := e.err(); err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// env Reads flag defaults from environment variables, collecting malformed values
+// A malformed value is an error rather than a silent default, since a wrong worker ID duplicates IDs
+type env struct {
+	invalid []string
+}
+
+// String returns the environment variable or def when unset
+func (e *env) String(name, def string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return def
+}
+
+// Int returns the environment variable as an integer or def when unset
+func (e *env) Int(name string, def int64) int64 {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not an integer", name, value))
+		return def
+	}
+	return n
+}
+
+// Bool returns the environment variable as a bool or def when unset
+func (e *env) Bool(name string, def bool) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not a boolean", name, value))
+		return def
+	}
+	return b
+}
+
+// Duration returns the environment variable as a duration or def when unset
+func (e *env) Duration(name string, def time.Duration) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not a duration", name, value))
+		return def
+	}
+	return d
+}
+
+// err returns an error listing every malformed variable, nil if there were none
+func (e *env) err() error {
+	if len(e.invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid environment: %s", strings.Join(e.invalid, "; "))
+}
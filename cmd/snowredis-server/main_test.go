@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// newFlagSet returns a flag set that reports errors instead of exiting
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("snowredis-server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// TestParseFlags tests defaults, environment variables and flags overriding them
+func TestParseFlags(t *testing.T) {
+	t.Setenv(snowflake.DefaultWorkerEnv, "7")
+	t.Setenv("SNOWREDIS_STRICT", "true")
+	t.Setenv("SNOWREDIS_LEASE_TTL", "10s")
+
+	cfg, err := parseFlags(newFlagSet(), []string{"-datacenter-id", "3", "-worker-id", "9", "-listen", ""})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if cfg.datacenterID != 3 || cfg.workerID != 9 || cfg.listen != "" {
+		t.Errorf("Flags not applied: %+v", cfg)
+	}
+	if !cfg.strict || cfg.leaseTTL != 10*time.Second {
+		t.Errorf("Environment not applied: %+v", cfg)
+	}
+	if cfg.respListen != ":6390" || cfg.epoch != snowflake.Epoch || cfg.sequenceBits != snowflake.DefaultLayout.SequenceBits {
+		t.Errorf("Defaults not applied: %+v", cfg)
+	}
+}
+
+// TestParseFlagsMalformedEnvironment tests that malformed variables are reported rather than defaulted
+func TestParseFlagsMalformedEnvironment(t *testing.T) {
+	tests := []struct {
+		name, value string
+	}{
+		{snowflake.DefaultWorkerEnv, "abc"},
+		{"SNOWREDIS_REDIS_DB", "1.5"},
+		{"SNOWREDIS_STRICT", "maybe"},
+		{"SNOWREDIS_LEASE_TTL", "30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			_, err := parseFlags(newFlagSet(), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("Expected an error naming %s, got %v", tt.name, err)
+			}
+		})
+	}
+
+	if _, err := parseFlags(newFlagSet(), []string{"-worker-id", "x"}); err == nil {
+		t.Error("Expected an error for a malformed flag")
+	}
+}
+
+// TestBuildGeneratorErrors tests that invalid configurations fail before serving
+func TestBuildGeneratorErrors(t *testing.T) {
+	base := func() *config {
+		cfg, err := parseFlags(newFlagSet(), nil)
+		if err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+		return cfg
+	}
+	tests := []struct {
+		name   string
+		modify func(*config)
+		want   error
+	}{
+		{"layout", func(c *config) { c.sequenceBits = 0 }, snowflake.ErrInvalidLayout},
+		{"worker", func(c *config) { c.datacenterID, c.workerID = 1, 32 }, snowflake.ErrInvalidWorker},
+		{"lifetime", func(c *config) { c.sequenceBits = 30 }, snowflake.ErrLifetimeTooShort},
+		{"fencing", func(c *config) { c.fencing = true }, snowflake.ErrFencingWithoutLease},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.modify(cfg)
+			if _, err := buildGenerator(cfg); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	cfg := base()
+	cfg.redisAddr = "127.0.0.1:1"
+	if _, err := buildGenerator(cfg); err == nil {
+		t.Error("Expected an error for an unreachable Redis")
+	}
+
+	sf, err := buildGenerator(base())
+	if err != nil {
+		t.Fatalf("Failed to build the default generator: %v", err)
+	}
+	sf.Cleanup()
+}
+
+// TestRunRequiresListener tests that the server refuses to start without a listener
+func TestRunRequiresListener(t *testing.T) {
+	cfg, err := parseFlags(newFlagSet(), []string{"-listen", "", "-resp-listen", ""})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if err := run(cfg); err == nil {
+		t.Error("Expected an error without listeners")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// release force-releases the lease of a worker slot left behind by a crashed node
+//
+//	snowredis release -redis-addr localhost:6379 -datacenter-id 1 -worker-id 2
+func (c *cli) release(args []string) error {
+	fs := c.flagSet("release")
+	rf := newRedisFlags(fs)
+	datacenterID := fs.Int64("datacenter-id", -1, "datacenter ID of the slot")
+	workerID := fs.Int64("worker-id", -1, "worker ID of the slot")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *datacenterID < 0 || *workerID < 0 {
+		return errors.New("-datacenter-id and -worker-id are required")
+	}
+
+	admin, err := c.admin(rf)
+	if err != nil {
+		return err
+	}
+	released, err := admin.ForceRelease(context.Background(), *datacenterID, *workerID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		fmt.Fprintf(c.stdout, "slot %d/%d was not leased\n", *datacenterID, *workerID)
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "released slot %d/%d\n", *datacenterID, *workerID)
+	return nil
+}
+
+// reserve reserves a worker slot for backfill, or returns it to the live nodes with -undo
+// -purge also deletes the slot's backfill records before the reservation is removed
+//
+//	snowredis reserve -redis-addr localhost:6379 -datacenter-id 31 -worker-id 31
+func (c *cli) reserve(args []string) error {
+	fs := c.flagSet("reserve")
+	layout := layoutFlags(fs)
+	rf := newRedisFlags(fs)
+	datacenterID := fs.Int64("datacenter-id", -1, "datacenter ID of the slot")
+	workerID := fs.Int64("worker-id", -1, "worker ID of the slot")
+	undo := fs.Bool("undo", false, "remove the reservation")
+	purge := fs.Bool("purge", false, "with -undo, delete the backfill records of the slot first; it must not be backfilled again")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *datacenterID < 0 || *workerID < 0 {
+		return errors.New("-datacenter-id and -worker-id are required")
+	}
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+
+	admin, err := c.admin(rf)
+	if err != nil {
+		return err
+	}
+	admin.SetLayout(*layout)
+	if *purge && !*undo {
+		return errors.New("-purge requires -undo")
+	}
+	if *undo {
+		if *purge {
+			n, err := admin.PurgeBackfill(context.Background(), *datacenterID, *workerID)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(c.stdout, "deleted %d backfill records of slot %d/%d\n", n, *datacenterID, *workerID)
+		}
+		if err := admin.UnreserveBackfill(context.Background(), *datacenterID, *workerID); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "slot %d/%d is no longer reserved for backfill\n", *datacenterID, *workerID)
+		return nil
+	}
+	if err := admin.ReserveBackfill(context.Background(), *datacenterID, *workerID); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "reserved slot %d/%d for backfill\n", *datacenterID, *workerID)
+	return nil
+}
+
+// reset shows the allocation counters and deletes them once confirmed
+//
+//	snowredis reset -redis-addr localhost:6379
+func (c *cli) reset(args []string) error {
+	fs := c.flagSet("reset")
+	rf := newRedisFlags(fs)
+	yes := fs.Bool("yes", false, "reset without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	admin, err := c.admin(rf)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	counters, err := admin.ResetCounters(ctx, false)
+	if err != nil {
+		return err
+	}
+	if len(counters) == 0 {
+		fmt.Fprintln(c.stdout, "no counters to reset")
+		return nil
+	}
+
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(c.stdout, "%s = %d\n", key, counters[key])
+	}
+
+	if !*yes {
+		fmt.Fprint(c.stdout, "Nodes allocated from these counters without a lease may be handed out again. Type \"reset\" to confirm: ")
+		answer, _ := bufio.NewReader(c.stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != "reset" {
+			return errors.New("not confirmed, counters left unchanged")
+		}
+	}
+	// Only the counters still holding the values shown are deleted
+	if err := admin.DeleteCounters(ctx, counters); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, "counters reset")
+	return nil
+}
+
+// purge deletes strict-mode keys of old IDs and keys without expiration
+//
+//	snowredis purge -redis-addr localhost:6379 -older-than 1h -dry-run
+func (c *cli) purge(args []string) error {
+	fs := c.flagSet("purge")
+	layout := layoutFlags(fs)
+	rf := newRedisFlags(fs)
+	olderThan := fs.Duration("older-than", time.Hour, "age after which the key of an ID is orphaned")
+	dryRun := fs.Bool("dry-run", false, "only count the orphaned keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+
+	admin, err := c.admin(rf)
+	if err != nil {
+		return err
+	}
+	n, err := admin.SetLayout(*layout).PurgeStrictKeys(context.Background(), *olderThan, *dryRun)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Fprintf(c.stdout, "%d orphaned strict-mode keys\n", n)
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "deleted %d orphaned strict-mode keys\n", n)
+	return nil
+}
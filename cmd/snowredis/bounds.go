@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// timeLayouts are the accepted forms of bounds arguments besides Unix milliseconds, read as UTC without a zone
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999", "2006-01-02 15:04:05.999", "2006-01-02"}
+
+// bounds prints the smallest ID of the start millisecond and the largest ID of the end millisecond
+//
+//	snowredis bounds 2026-01-01T00:00:00Z 2026-01-01T23:59:59.999Z
+//	snowredis bounds 1767225600000
+func (c *cli) bounds(args []string) error {
+	fs := c.flagSet("bounds")
+	layout := layoutFlags(fs)
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return errors.New("usage: snowredis bounds START [END]")
+	}
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+
+	start, err := parseTime(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	end := start
+	if fs.NArg() == 2 {
+		if end, err = parseTime(fs.Arg(1)); err != nil {
+			return err
+		}
+	}
+	minID, maxID, err := layout.RangeFor(start, end)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		return json.NewEncoder(c.stdout).Encode(map[string]string{
+			"min": strconv.FormatInt(minID, 10),
+			"max": strconv.FormatInt(maxID, 10),
+		})
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "min\t%d\t%s\n", minID, formatTime(start))
+	fmt.Fprintf(tw, "max\t%d\t%s\n", maxID, formatTime(end))
+	return tw.Flush()
+}
+
+// parseTime parses Unix milliseconds or one of timeLayouts
+func parseTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, want RFC 3339, 2006-01-02[ 15:04:05] or Unix milliseconds", s)
+}
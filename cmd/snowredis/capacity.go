@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// capacityJSON JSON form of snowflake.Capacity
+type capacityJSON struct {
+	ExhaustsAt       string `json:"exhausts_at"`
+	RemainingDays    int64  `json:"remaining_days"`
+	MaxNodes         int64  `json:"max_nodes"`
+	PeakIDsPerSecond int64  `json:"peak_ids_per_second"`
+}
+
+// capacity prints when the layout runs out of timestamps, how many nodes it holds and their peak rate
+//
+//	snowredis capacity -sequence-bits 14 -worker-bits 3
+func (c *cli) capacity(args []string) error {
+	fs := c.flagSet("capacity")
+	layout := layoutFlags(fs)
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: snowredis capacity [flags]")
+	}
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+
+	capacity := layout.Capacity(time.Now())
+	days := int64(capacity.Remaining / (24 * time.Hour))
+	if *format == "json" {
+		return json.NewEncoder(c.stdout).Encode(capacityJSON{
+			ExhaustsAt:       formatTime(capacity.ExhaustsAt),
+			RemainingDays:    days,
+			MaxNodes:         capacity.MaxNodes,
+			PeakIDsPerSecond: capacity.PeakIDsPerSecond,
+		})
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "exhausts at\t%s\n", formatTime(capacity.ExhaustsAt))
+	fmt.Fprintf(tw, "remaining\t%d days\n", days)
+	fmt.Fprintf(tw, "max nodes\t%d\n", capacity.MaxNodes)
+	fmt.Fprintf(tw, "peak IDs/s per node\t%d\n", capacity.PeakIDsPerSecond)
+	return tw.Flush()
+}
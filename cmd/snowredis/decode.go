@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// decode prints the parts of IDs given as arguments, or read whitespace-separated from stdin
+//
+//	snowredis decode 634733393443164160
+//	cat ids.txt | snowredis decode -format json
+func (c *cli) decode(args []string) error {
+	fs := c.flagSet("decode")
+	layout := layoutFlags(fs)
+	format := fs.String("format", "table", "output format: table or json (one object per line)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+
+	next, sourceErr := argsSource(fs.Args())
+	if fs.NArg() == 0 {
+		next, sourceErr = readerSource(c.stdin)
+	}
+
+	var emit func(snowflake.DecodedID)
+	var flush func() error
+	if *format == "json" {
+		enc := json.NewEncoder(c.stdout)
+		emit = func(d snowflake.DecodedID) { _ = enc.Encode(d) }
+		flush = func() error { return nil }
+	} else {
+		tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTIME\tDATACENTER\tWORKER\tSEQUENCE")
+		emit = func(d snowflake.DecodedID) {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", d.ID, d.Time, d.DatacenterID, d.WorkerID, d.Sequence)
+		}
+		flush = tw.Flush
+	}
+
+	invalid := 0
+	for raw, ok := next(); ok; raw, ok = next() {
+		d, err := decodeID(*layout, raw)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "snowredis decode: %v\n", err)
+			invalid++
+			continue
+		}
+		emit(d)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if err := sourceErr(); err != nil {
+		return fmt.Errorf("reading IDs: %w", err)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d invalid IDs", invalid)
+	}
+	return nil
+}
+
+// decodeID parses and decodes a single ID
+func decodeID(layout snowflake.Layout, raw string) (snowflake.DecodedID, error) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return snowflake.DecodedID{}, fmt.Errorf("invalid id %q", raw)
+	}
+	parts, err := layout.Decode(id)
+	if err != nil {
+		return snowflake.DecodedID{}, fmt.Errorf("id %q: %w", raw, err)
+	}
+	return parts.Describe(id), nil
+}
+
+// argsSource returns an iterator over the command line arguments and a function reporting read errors
+func argsSource(args []string) (func() (string, bool), func() error) {
+	return func() (string, bool) {
+		if len(args) == 0 {
+			return "", false
+		}
+		arg := args[0]
+		args = args[1:]
+		return arg, true
+	}, func() error { return nil }
+}
+
+// readerSource returns an iterator over the whitespace-separated words of a reader
+// and a function reporting the error that ended it, nil at the end of input
+func readerSource(r io.Reader) (func() (string, bool), func() error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	return func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		return scanner.Text(), true
+	}, scanner.Err
+}
+
+// formatTime renders a time the way decode does
+func formatTime(t time.Time) string {
+	return t.UTC().Format(snowflake.DecodedTimeFormat)
+}
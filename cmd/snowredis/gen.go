@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// genBatchSize is the number of IDs generated per GenerateN call
+const genBatchSize = 10000
+
+// gen generates IDs and prints one per line
+//
+//	snowredis gen -n 10
+//	snowredis gen -n 10 -redis-addr localhost:6379
+func (c *cli) gen(args []string) error {
+	fs := c.flagSet("gen")
+	n := fs.Int("n", 1, "number of IDs to generate")
+	layout := layoutFlags(fs)
+	rf := newRedisFlags(fs)
+	datacenterID := fs.Int64("datacenter-id", fs.env.Int(snowflake.DefaultDatacenterEnv, 0), "datacenter ID, 0 to allocate ("+snowflake.DefaultDatacenterEnv+")")
+	workerID := fs.Int64("worker-id", fs.env.Int(snowflake.DefaultWorkerEnv, 0), "worker ID, 0 to allocate ("+snowflake.DefaultWorkerEnv+")")
+	strict := fs.Bool("strict", fs.env.Bool("SNOWREDIS_STRICT", false), "enable strict mode (SNOWREDIS_STRICT)")
+	leaseTTL := fs.Duration("lease-ttl", fs.env.Duration("SNOWREDIS_LEASE_TTL", 10*time.Second), "worker slot lease TTL when using Redis (SNOWREDIS_LEASE_TTL)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return fmt.Errorf("-n must be at least 1, got %d", *n)
+	}
+
+	builder := snowflake.NewBuilder().
+		SetLayout(*layout).
+		SetDatacenterID(*datacenterID).
+		SetWorkerID(*workerID).
+		SetStrictMode(*strict)
+	if rf.addr != "" {
+		client, err := c.connect(rf)
+		if err != nil {
+			return err
+		}
+		// Leasing keeps a one-off run off the slots of live nodes
+		builder.SetRedisClient(client).SetKeyPrefix(rf.keyPrefix).SetLeaseTTL(*leaseTTL)
+	}
+	sf, err := builder.Build()
+	if err != nil {
+		return err
+	}
+	defer sf.Cleanup()
+
+	w := bufio.NewWriter(c.stdout)
+	for remaining := *n; remaining > 0; {
+		batch := remaining
+		if batch > genBatchSize {
+			batch = genBatchSize
+		}
+		ids, err := sf.GenerateN(batch)
+		if err != nil {
+			_ = w.Flush()
+			return err
+		}
+		for _, id := range ids {
+			_, _ = w.WriteString(strconv.FormatInt(id, 10) + "\n")
+		}
+		remaining -= batch
+	}
+	return w.Flush()
+}
@@ -0,0 +1,237 @@
+// Command snowredis generates, decodes and inspects snowflake IDs from the terminal.
+//
+// Flags shared with snowredis-server can also be set through the same SNOWREDIS_* environment variables.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+const usage = `Usage: snowredis <command> [flags] [args]
+
+Commands:
+  gen       generate IDs locally or against Redis
+  decode    decode IDs given as arguments or read from stdin
+  bounds    print the smallest and largest ID of a time range
+  capacity  print the exhaustion date, node count and peak rate of a layout
+  slots     list the worker slots leased in Redis
+  release   force-release the lease of a worker slot
+  reserve   reserve a worker slot for backfill, or undo the reservation
+  reset     reset the allocation counters in Redis after confirmation
+  purge     delete orphaned strict-mode keys from Redis
+
+Run "snowredis <command> -h" for the flags of a command.
+`
+
+// cli Input and output streams and the Redis connection shared by the subcommands
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	dial   func(cfg *redis.Config) (redis.Client, error) // Connects to Redis, replaced in tests
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, dial: redis.NewRedisClient}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run dispatches to the subcommand named by the first argument
+// @param args - []string command line arguments without the program name
+// @return int - the process exit code
+func (c *cli) run(args []string) int {
+	commands := map[string]func([]string) error{
+		"gen":      c.gen,
+		"decode":   c.decode,
+		"bounds":   c.bounds,
+		"capacity": c.capacity,
+		"slots":    c.slots,
+		"release":  c.release,
+		"reserve":  c.reserve,
+		"reset":    c.reset,
+		"purge":    c.purge,
+	}
+	if len(args) == 0 {
+		fmt.Fprint(c.stderr, usage)
+		return 2
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(c.stderr, "snowredis: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	err := command(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "snowredis %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// flagSet Flag set of a subcommand whose defaults are read from the environment
+type flagSet struct {
+	*flag.FlagSet
+	env env
+}
+
+// flagSet creates the flag set of a subcommand, reporting parse errors to stderr
+func (c *cli) flagSet(name string) *flagSet {
+	fs := &flagSet{FlagSet: flag.NewFlagSet("snowredis "+name, flag.ContinueOnError)}
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// Parse rejects malformed environment variables read for the defaults, then parses the arguments
+// @param args - []string subcommand arguments
+// @return error - the malformed environment variables or any flag parsing error
+func (fs *flagSet) Parse(args []string) error {
+	if err := fs.env.err(); err != nil {
+		return err
+	}
+	return fs.FlagSet.Parse(args)
+}
+
+// layoutFlags registers the epoch and bit width flags, starting from DefaultLayout
+// @param fs - *flagSet to register the flags on
+// @return *snowflake.Layout - the layout filled in by parsing
+func layoutFlags(fs *flagSet) *snowflake.Layout {
+	layout := snowflake.DefaultLayout
+	fs.Int64Var(&layout.Epoch, "epoch", fs.env.Int("SNOWREDIS_EPOCH", layout.Epoch), "epoch in milliseconds since the Unix epoch (SNOWREDIS_EPOCH)")
+	fs.UintVar(&layout.DatacenterBits, "datacenter-bits", uint(fs.env.Int("SNOWREDIS_DATACENTER_BITS", int64(layout.DatacenterBits))), "datacenter ID bits (SNOWREDIS_DATACENTER_BITS)")
+	fs.UintVar(&layout.WorkerBits, "worker-bits", uint(fs.env.Int("SNOWREDIS_WORKER_BITS", int64(layout.WorkerBits))), "worker ID bits (SNOWREDIS_WORKER_BITS)")
+	fs.UintVar(&layout.SequenceBits, "sequence-bits", uint(fs.env.Int("SNOWREDIS_SEQUENCE_BITS", int64(layout.SequenceBits))), "sequence bits (SNOWREDIS_SEQUENCE_BITS)")
+	return &layout
+}
+
+// redisFlags Redis connection flags
+type redisFlags struct {
+	addr      string
+	password  string
+	db        int
+	keyPrefix string
+}
+
+// newRedisFlags registers the Redis connection flags
+// @param fs - *flagSet to register the flags on
+// @return *redisFlags - the connection settings filled in by parsing
+func newRedisFlags(fs *flagSet) *redisFlags {
+	rf := &redisFlags{}
+	fs.StringVar(&rf.addr, "redis-addr", fs.env.String("SNOWREDIS_REDIS_ADDR", ""), "Redis address (SNOWREDIS_REDIS_ADDR)")
+	fs.StringVar(&rf.password, "redis-password", fs.env.String("SNOWREDIS_REDIS_PASSWORD", ""), "Redis password (SNOWREDIS_REDIS_PASSWORD)")
+	fs.IntVar(&rf.db, "redis-db", int(fs.env.Int("SNOWREDIS_REDIS_DB", 0)), "Redis database (SNOWREDIS_REDIS_DB)")
+	fs.StringVar(&rf.keyPrefix, "key-prefix", fs.env.String("SNOWREDIS_KEY_PREFIX", snowflake.DefaultKeyPrefix), "prefix of the Redis keys (SNOWREDIS_KEY_PREFIX)")
+	return rf
+}
+
+// connect connects to the configured Redis server
+// @param rf - *redisFlags with the connection settings
+// @return redis.Client - the connected client
+// @return error - any error that occurred while connecting
+func (c *cli) connect(rf *redisFlags) (redis.Client, error) {
+	return c.dial(&redis.Config{Addr: rf.addr, Pwd: rf.password, Db: rf.db})
+}
+
+// admin connects to the configured Redis server for admin operations
+// @param rf - *redisFlags with the connection settings
+// @return *snowflake.Admin - the admin of the coordination keys
+// @return error - an error if no address is configured or the connection fails
+func (c *cli) admin(rf *redisFlags) (*snowflake.Admin, error) {
+	if rf.addr == "" {
+		return nil, errors.New("-redis-addr is required")
+	}
+	client, err := c.connect(rf)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		return nil, err
+	}
+	return admin.SetKeyPrefix(rf.keyPrefix), nil
+}
+
+// checkFormat validates the -format flag
+func checkFormat(format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q, want table or json", format)
+	}
+	return nil
+}
+
+// env Reads flag defaults from environment variables, collecting malformed values
+// A malformed value is an error rather than a silent default, since a wrong worker ID duplicates IDs
+type env struct {
+	invalid []string
+}
+
+// String returns the environment variable or def when unset
+func (e *env) String(name, def string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return def
+}
+
+// Int returns the environment variable as an integer or def when unset
+func (e *env) Int(name string, def int64) int64 {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not an integer", name, value))
+		return def
+	}
+	return n
+}
+
+// Bool returns the environment variable as a bool or def when unset
+func (e *env) Bool(name string, def bool) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not a boolean", name, value))
+		return def
+	}
+	return b
+}
+
+// Duration returns the environment variable as a duration or def when unset
+func (e *env) Duration(name string, def time.Duration) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not a duration", name, value))
+		return def
+	}
+	return d
+}
+
+// err returns an error listing every malformed variable, nil if there were none
+func (e *env) err() error {
+	if len(e.invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid environment: %s", strings.Join(e.invalid, "; "))
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// errReader fails every read
+type errReader struct{ err error }
+
+// Read implements io.Reader
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// newCLI returns a cli writing to buffers and connecting to the given mock client
+func newCLI(client *mock.RedisClient, stdin string) (*cli, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c := &cli{
+		stdin:  strings.NewReader(stdin),
+		stdout: stdout,
+		stderr: stderr,
+		dial:   func(*redis.Config) (redis.Client, error) { return client, nil },
+	}
+	return c, stdout, stderr
+}
+
+// leaseNode builds a node holding a lease in the mock client, released when the test ends
+func leaseNode(t *testing.T, client *mock.RedisClient, strict bool) *snowflake.RedisSnowflake {
+	t.Helper()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(time.Minute).SetStrictMode(strict).Build()
+	if err != nil {
+		t.Fatalf("Failed to build node: %v", err)
+	}
+	t.Cleanup(sf.Cleanup)
+	return sf
+}
+
+// TestCLI drives the subcommands with a mock Redis client and checks exit code and output
+func TestCLI(t *testing.T) {
+	const id = "634733393443164160"
+	cases := []struct {
+		name   string
+		args   []string
+		stdin  string
+		setup  func(t *testing.T, client *mock.RedisClient)
+		code   int
+		stdout []string
+		stderr []string
+	}{
+		{name: "no command", args: nil, code: 2, stderr: []string{"Usage"}},
+		{name: "unknown command", args: []string{"nope"}, code: 2, stderr: []string{`unknown command "nope"`}},
+		{name: "help", args: []string{"gen", "-h"}, code: 0},
+		{name: "gen", args: []string{"gen", "-n", "3", "-datacenter-id", "1", "-worker-id", "1"}, code: 0},
+		{name: "gen invalid count", args: []string{"gen", "-n", "0"}, code: 1, stderr: []string{"-n must be at least 1"}},
+		{name: "gen redis", args: []string{"gen", "-n", "2", "-redis-addr", "mock", "-strict"}, code: 0},
+		{name: "decode args", args: []string{"decode", id}, code: 0, stdout: []string{"DATACENTER", id}},
+		{name: "decode stdin json", args: []string{"decode", "-format", "json"}, stdin: id + "\n" + id, code: 0, stdout: []string{`"id":"` + id + `"`}},
+		{name: "decode invalid", args: []string{"decode", "abc", id}, code: 1, stdout: []string{id}, stderr: []string{"abc"}},
+		{name: "decode format", args: []string{"decode", "-format", "xml", id}, code: 1, stderr: []string{"xml"}},
+		{name: "bounds", args: []string{"bounds", "2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z"}, code: 0},
+		{name: "capacity", args: []string{"capacity"}, code: 0},
+		{name: "slots requires address", args: []string{"slots"}, code: 1, stderr: []string{"-redis-addr is required"}},
+		{
+			name: "slots", args: []string{"slots", "-redis-addr", "mock"}, code: 0,
+			setup:  func(t *testing.T, client *mock.RedisClient) { leaseNode(t, client, false) },
+			stdout: []string{"HOLDER"},
+		},
+		{
+			name: "release", args: []string{"release", "-redis-addr", "mock", "-datacenter-id", "0", "-worker-id", "0"}, code: 0,
+			setup:  func(t *testing.T, client *mock.RedisClient) { leaseNode(t, client, false) },
+			stdout: []string{"released slot 0/0"},
+		},
+		{name: "release free slot", args: []string{"release", "-redis-addr", "mock", "-datacenter-id", "0", "-worker-id", "0"}, code: 0, stdout: []string{"was not leased"}},
+		{name: "release requires slot", args: []string{"release", "-redis-addr", "mock"}, code: 1, stderr: []string{"-datacenter-id and -worker-id are required"}},
+		{name: "reserve", args: []string{"reserve", "-redis-addr", "mock", "-datacenter-id", "31", "-worker-id", "31"}, code: 0, stdout: []string{"reserved slot 31/31"}},
+		{name: "reserve purge requires undo", args: []string{"reserve", "-redis-addr", "mock", "-datacenter-id", "31", "-worker-id", "31", "-purge"}, code: 1, stderr: []string{"-purge requires -undo"}},
+		{
+			name: "reserve undo purge", args: []string{"reserve", "-redis-addr", "mock", "-datacenter-id", "31", "-worker-id", "31", "-undo", "-purge"}, code: 0,
+			setup: func(t *testing.T, client *mock.RedisClient) {
+				admin, _ := snowflake.NewAdmin(client)
+				if err := admin.ReserveBackfill(context.Background(), 31, 31); err != nil {
+					t.Fatalf("Failed to reserve: %v", err)
+				}
+			},
+			stdout: []string{"deleted 0 backfill records of slot 31/31", "no longer reserved"},
+		},
+		{name: "reset nothing", args: []string{"reset", "-redis-addr", "mock"}, code: 0, stdout: []string{"no counters to reset"}},
+		{
+			name: "reset yes", args: []string{"reset", "-redis-addr", "mock", "-yes"}, code: 0,
+			setup:  func(t *testing.T, client *mock.RedisClient) { leaseNode(t, client, false) },
+			stdout: []string{"next_slot = 1", "counters reset"},
+		},
+		{
+			name: "reset confirmed", args: []string{"reset", "-redis-addr", "mock"}, stdin: "reset\n", code: 0,
+			setup:  func(t *testing.T, client *mock.RedisClient) { leaseNode(t, client, false) },
+			stdout: []string{"Type \"reset\" to confirm", "counters reset"},
+		},
+		{
+			name: "reset declined", args: []string{"reset", "-redis-addr", "mock"}, stdin: "no\n", code: 1,
+			setup:  func(t *testing.T, client *mock.RedisClient) { leaseNode(t, client, false) },
+			stderr: []string{"not confirmed"},
+		},
+		{
+			name: "purge dry run", args: []string{"purge", "-redis-addr", "mock", "-older-than", "0s", "-dry-run"}, code: 0,
+			setup: func(t *testing.T, client *mock.RedisClient) {
+				if _, err := leaseNode(t, client, true).Generate(); err != nil {
+					t.Fatalf("Failed to generate: %v", err)
+				}
+			},
+			stdout: []string{"orphaned strict-mode keys"},
+		},
+		{name: "purge", args: []string{"purge", "-redis-addr", "mock"}, code: 0, stdout: []string{"deleted 0 orphaned strict-mode keys"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := mock.NewMockRedisClient()
+			if tc.setup != nil {
+				tc.setup(t, client)
+			}
+			c, stdout, stderr := newCLI(client, tc.stdin)
+			if code := c.run(tc.args); code != tc.code {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", tc.code, code, stderr)
+			}
+			for _, want := range tc.stdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Expected stdout to contain %q, got %q", want, stdout)
+				}
+			}
+			for _, want := range tc.stderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("Expected stderr to contain %q, got %q", want, stderr)
+				}
+			}
+		})
+	}
+}
+
+// TestCLIGenOutput tests that gen prints the requested number of distinct IDs
+func TestCLIGenOutput(t *testing.T) {
+	c, stdout, stderr := newCLI(mock.NewMockRedisClient(), "")
+	if code := c.run([]string{"gen", "-n", "25000", "-datacenter-id", "1", "-worker-id", "1"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr)
+	}
+	lines := strings.Fields(stdout.String())
+	if len(lines) != 25000 {
+		t.Fatalf("Expected 25000 IDs, got %d", len(lines))
+	}
+	seen := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		if _, err := strconv.ParseInt(line, 10, 64); err != nil {
+			t.Fatalf("Invalid ID %q", line)
+		}
+		if seen[line] {
+			t.Fatalf("Duplicate ID %s", line)
+		}
+		seen[line] = true
+	}
+}
+
+// TestCLIDecodeReadError tests that a failing stdin is reported instead of ending the input silently
+func TestCLIDecodeReadError(t *testing.T) {
+	c, _, stderr := newCLI(mock.NewMockRedisClient(), "")
+	c.stdin = errReader{err: errors.New("broken pipe")}
+	if code := c.run([]string{"decode"}); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "reading IDs: broken pipe") {
+		t.Errorf("Expected the read error, got %q", stderr)
+	}
+}
+
+// TestCLIEnvironment tests that environment defaults are applied and malformed ones are rejected
+func TestCLIEnvironment(t *testing.T) {
+	t.Run("malformed", func(t *testing.T) {
+		t.Setenv("SNOWREDIS_WORKER_ID", "abc")
+		t.Setenv("SNOWREDIS_STRICT", "maybe")
+		c, _, stderr := newCLI(mock.NewMockRedisClient(), "")
+		if code := c.run([]string{"gen"}); code != 1 {
+			t.Fatalf("Expected exit code 1, got %d", code)
+		}
+		for _, want := range []string{`SNOWREDIS_WORKER_ID="abc" is not an integer`, `SNOWREDIS_STRICT="maybe" is not a boolean`} {
+			if !strings.Contains(stderr.String(), want) {
+				t.Errorf("Expected stderr to contain %q, got %q", want, stderr)
+			}
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		t.Setenv("SNOWREDIS_STRICT", "true")
+		client := mock.NewMockRedisClient()
+		c, stdout, stderr := newCLI(client, "")
+		if code := c.run([]string{"gen", "-redis-addr", "mock"}); code != 0 {
+			t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr)
+		}
+		id := strings.TrimSpace(stdout.String())
+		if _, err := client.Get(context.Background(), "snowflake:id:"+id); err != nil {
+			t.Errorf("Expected strict mode to record ID %s: %v", id, err)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// slotJSON JSON form of snowflake.Slot
+type slotJSON struct {
+	DatacenterID int64  `json:"datacenter_id"`
+	WorkerID     int64  `json:"worker_id"`
+	Holder       string `json:"holder"`
+	AcquiredAt   string `json:"acquired_at,omitempty"`
+	TTLMillis    int64  `json:"ttl_ms"`
+}
+
+// slots lists the worker slots leased in Redis and their holders
+//
+//	snowredis slots -redis-addr localhost:6379
+func (c *cli) slots(args []string) error {
+	fs := c.flagSet("slots")
+	rf := newRedisFlags(fs)
+	format := fs.String("format", "table", "output format: table or json (one object per line)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	admin, err := c.admin(rf)
+	if err != nil {
+		return err
+	}
+	slots, err := admin.ListSlots(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(c.stdout)
+		for _, slot := range slots {
+			if err := enc.Encode(toSlotJSON(slot)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATACENTER\tWORKER\tHOLDER\tACQUIRED\tTTL")
+	for _, slot := range slots {
+		s := toSlotJSON(slot)
+		ttl := "none"
+		if slot.TTL >= 0 {
+			ttl = slot.TTL.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", s.DatacenterID, s.WorkerID, s.Holder, s.AcquiredAt, ttl)
+	}
+	return tw.Flush()
+}
+
+// toSlotJSON converts a slot for output
+func toSlotJSON(slot snowflake.Slot) slotJSON {
+	s := slotJSON{
+		DatacenterID: slot.DatacenterID,
+		WorkerID:     slot.WorkerID,
+		Holder:       slot.Holder,
+		TTLMillis:    slot.TTL.Milliseconds(),
+	}
+	if slot.AcquiredAt > 0 {
+		s.AcquiredAt = formatTime(time.Unix(0, slot.AcquiredAt*int64(time.Millisecond)))
+	}
+	return s
+}
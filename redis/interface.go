@@ -3,9 +3,13 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNil is returned by Get when the key does not exist
+var ErrNil = errors.New("redis: nil")
+
 // Client Interface defining basic Redis operations required for ID allocation.
 type Client interface {
 	// SetNX sets a key-value pair if the key does not exist
@@ -31,3 +35,93 @@ type Client interface {
 	// @return error - error if any occurred during the operation
 	Del(ctx context.Context, keys ...string) (int64, error)
 }
+
+// KeyValueClient Optional interface for clients that can read and overwrite plain string keys.
+// Features that need it check for it with a type assertion, so plain Client implementations keep working.
+type KeyValueClient interface {
+	// Get gets the value of a key
+	// @param ctx - context for the operation
+	// @param key - string representing the key to get
+	// @return string - the value of the key
+	// @return error - ErrNil if the key does not exist, or any other error that occurred
+	Get(ctx context.Context, key string) (string, error)
+
+	// Set sets the value of a key, overwriting any existing value
+	// @param ctx - context for the operation
+	// @param key - string representing the key to set
+	// @param value - interface{} representing the value to set
+	// @param expiration - time.Duration representing the expiration time (0 means no expiration)
+	// @return error - error if any occurred during the operation
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+}
+
+// TimeClient Optional interface for clients that can read the Redis server clock.
+type TimeClient interface {
+	// Time returns the current Redis server time (the TIME command)
+	// @param ctx - context for the operation
+	// @return time.Time - the server time
+	// @return error - error if any occurred during the operation
+	Time(ctx context.Context) (time.Time, error)
+}
+
+// ExpireClient Optional interface for clients that can manage key expiration.
+type ExpireClient interface {
+	// Expire sets a timeout on a key
+	// @param ctx - context for the operation
+	// @param key - string representing the key
+	// @param expiration - time.Duration after which the key expires
+	// @return bool - true if the timeout was set, false if the key does not exist
+	// @return error - error if any occurred during the operation
+	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
+
+	// TTL returns the remaining time to live of a key
+	// @param ctx - context for the operation
+	// @param key - string representing the key
+	// @return time.Duration - the remaining time to live, -1 if the key has no expiration
+	// @return error - ErrNil if the key does not exist, or any other error that occurred
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// ScanClient Optional interface for clients that can iterate over the keyspace.
+type ScanClient interface {
+	// Scan returns a page of keys matching a glob pattern (the SCAN command)
+	// @param ctx - context for the operation
+	// @param cursor - uint64 cursor returned by the previous call, 0 to start
+	// @param match - string glob pattern the keys must match
+	// @param count - int64 hint for the number of keys to return
+	// @return []string - the keys of this page
+	// @return uint64 - the cursor for the next call, 0 once the iteration is complete
+	// @return error - error if any occurred during the operation
+	Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error)
+}
+
+// CompareAndDeleteClient Optional interface for clients that can delete a key only while it holds a value.
+type CompareAndDeleteClient interface {
+	// CompareAndDelete deletes a key if it still holds the given value, as one atomic step
+	// @param ctx - context for the operation
+	// @param key - string representing the key to delete
+	// @param value - string the key must hold
+	// @return bool - true if the key was deleted, false if it is missing or holds another value
+	// @return error - error if any occurred during the operation
+	CompareAndDelete(ctx context.Context, key string, value string) (bool, error)
+}
+
+// CompareAndExpireClient Optional interface for clients that can extend a key's timeout only while it holds a value.
+type CompareAndExpireClient interface {
+	// CompareAndExpire sets a timeout on a key if it still holds the given value, as one atomic step
+	// @param ctx - context for the operation
+	// @param key - string representing the key
+	// @param value - string the key must hold
+	// @param expiration - time.Duration after which the key expires
+	// @return bool - true if the timeout was set, false if the key is missing or holds another value
+	// @return error - error if any occurred during the operation
+	CompareAndExpire(ctx context.Context, key string, value string, expiration time.Duration) (bool, error)
+}
+
+// PingClient Optional interface for clients that can check the connection to Redis.
+type PingClient interface {
+	// Ping checks that the server is reachable (the PING command)
+	// @param ctx - context for the operation
+	// @return error - error if the server could not be reached
+	Ping(ctx context.Context) error
+}
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -49,6 +50,122 @@ func (r *Wrapper) Del(ctx context.Context, keys ...string) (int64, error) {
 	return r.client.Del(ctx, keys...).Result()
 }
 
+// Get gets the value of a key
+// @param ctx - context for the operation
+// @param key - string representing the key to get
+// @return string - the value of the key
+// @return error - ErrNil if the key does not exist, or any other error that occurred
+func (r *Wrapper) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNil
+	}
+	return value, err
+}
+
+// Set sets the value of a key, overwriting any existing value
+// @param ctx - context for the operation
+// @param key - string representing the key to set
+// @param value - interface{} representing the value to set
+// @param expiration - time.Duration representing the expiration time (0 means no expiration)
+// @return error - error if any occurred during the operation
+func (r *Wrapper) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return r.client.Set(ctx, key, value, expiration).Err()
+}
+
+// Time returns the current Redis server time (the TIME command)
+// @param ctx - context for the operation
+// @return time.Time - the server time
+// @return error - error if any occurred during the operation
+func (r *Wrapper) Time(ctx context.Context) (time.Time, error) {
+	return r.client.Time(ctx).Result()
+}
+
+// Expire sets a timeout on a key
+// @param ctx - context for the operation
+// @param key - string representing the key
+// @param expiration - time.Duration after which the key expires
+// @return bool - true if the timeout was set, false if the key does not exist
+// @return error - error if any occurred during the operation
+func (r *Wrapper) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return r.client.PExpire(ctx, key, expiration).Result()
+}
+
+// TTL returns the remaining time to live of a key
+// @param ctx - context for the operation
+// @param key - string representing the key
+// @return time.Duration - the remaining time to live, -1 if the key has no expiration
+// @return error - ErrNil if the key does not exist, or any other error that occurred
+func (r *Wrapper) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL reports -2 for a missing key and -1 for a key without expiration
+	if ttl == -2 {
+		return 0, ErrNil
+	}
+	return ttl, nil
+}
+
+// Scan returns a page of keys matching a glob pattern (the SCAN command)
+// @param ctx - context for the operation
+// @param cursor - uint64 cursor returned by the previous call, 0 to start
+// @param match - string glob pattern the keys must match
+// @param count - int64 hint for the number of keys to return
+// @return []string - the keys of this page
+// @return uint64 - the cursor for the next call, 0 once the iteration is complete
+// @return error - error if any occurred during the operation
+func (r *Wrapper) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	return r.client.Scan(ctx, cursor, match, count).Result()
+}
+
+// compareAndDelete deletes KEYS[1] only while it holds ARGV[1]
+var compareAndDelete = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// CompareAndDelete deletes a key if it still holds the given value, as one atomic step
+// @param ctx - context for the operation
+// @param key - string representing the key to delete
+// @param value - string the key must hold
+// @return bool - true if the key was deleted, false if it is missing or holds another value
+// @return error - error if any occurred during the operation
+func (r *Wrapper) CompareAndDelete(ctx context.Context, key string, value string) (bool, error) {
+	n, err := compareAndDelete.Run(ctx, r.client, []string{key}, value).Int64()
+	return n > 0, err
+}
+
+// compareAndPExpire sets the timeout of KEYS[1] to ARGV[2] milliseconds only while it holds ARGV[1]
+var compareAndPExpire = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// CompareAndExpire sets a timeout on a key if it still holds the given value, as one atomic step
+// @param ctx - context for the operation
+// @param key - string representing the key
+// @param value - string the key must hold
+// @param expiration - time.Duration after which the key expires
+// @return bool - true if the timeout was set, false if the key is missing or holds another value
+// @return error - error if any occurred during the operation
+func (r *Wrapper) CompareAndExpire(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	n, err := compareAndPExpire.Run(ctx, r.client, []string{key}, value, expiration.Milliseconds()).Int64()
+	return n > 0, err
+}
+
+// Ping checks that the server is reachable (the PING command)
+// @param ctx - context for the operation
+// @return error - error if the server could not be reached
+func (r *Wrapper) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 // NewClient Creates and returns a Redis client instance
 // @param cfg - *Config containing Redis connection configuration
 // @return *Wrapper - the created Redis wrapper instance
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// Generator ID source served by HTTPHandler and RESPServer, implemented by *snowflake.RedisSnowflake
+// Accepting the interface lets the servers front other generators and fakes in tests
+type Generator interface {
+	// GenerateContext returns a unique ID, giving up when ctx is done
+	// @param ctx - context for the operation
+	// @return int64 - the generated ID
+	// @return error - any error that occurred during generation
+	GenerateContext(ctx context.Context) (int64, error)
+	// GenerateNContext returns n unique IDs in generation order, giving up when ctx is done
+	// @param ctx - context for the operation
+	// @param n - int number of IDs to generate
+	// @return []int64 - the generated IDs
+	// @return error - any error that occurred during generation
+	GenerateNContext(ctx context.Context, n int) ([]int64, error)
+	// Decode splits an ID into its parts
+	// @param id - int64 ID to decode
+	// @return snowflake.Components - the decoded parts
+	// @return error - snowflake.ErrInvalidID if the ID cannot have been generated
+	Decode(id int64) (snowflake.Components, error)
+	// Health reports whether IDs can currently be issued
+	// @param ctx - context for the operation
+	// @return error - nil while healthy, otherwise the reason
+	Health(ctx context.Context) error
+}
+
+// Reporter Optional interface for generators that report runtime statistics and their layout
+// HTTPHandler serves /metrics and RESPServer fills INFO only for generators implementing it
+type Reporter interface {
+	// Stats returns a snapshot of the runtime statistics
+	// @return snowflake.Stats - the current statistics
+	Stats() snowflake.Stats
+	// Layout returns the layout IDs are generated with
+	// @return snowflake.Layout - the layout including its epoch
+	Layout() snowflake.Layout
+}
+
+var (
+	_ Generator = (*snowflake.RedisSnowflake)(nil)
+	_ Reporter  = (*snowflake.RedisSnowflake)(nil)
+)
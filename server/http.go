@@ -0,0 +1,165 @@
+// Package server exposes a snowflake ID generator over the network.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxBatchSize is the largest number of IDs a single batch request may ask for
+const MaxBatchSize = 10000
+
+// HTTPHandler Serves ID generation, decoding and health endpoints over HTTP
+//
+//	GET /id             one ID
+//	GET /ids?n=100      a batch of IDs
+//	GET /decode?id=123  the parts of an ID
+//	GET /health         200 while the generator is usable
+//	GET /metrics        statistics in the Prometheus text format
+//
+// Responses are JSON unless ?format=text is given or the Accept header prefers text/plain.
+// IDs are rendered as JSON strings because they exceed the 2^53 integer range of JavaScript.
+type HTTPHandler struct {
+	generator Generator
+	mux       *http.ServeMux
+}
+
+// NewHTTPHandler Creates an HTTP handler backed by the generator
+// /metrics is served only if the generator implements Reporter
+// @param generator - Generator generating the IDs
+// @return *HTTPHandler - the created handler
+func NewHTTPHandler(generator Generator) *HTTPHandler {
+	h := &HTTPHandler{generator: generator, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/id", h.handleID)
+	h.mux.HandleFunc("/ids", h.handleIDs)
+	h.mux.HandleFunc("/decode", h.handleDecode)
+	h.mux.HandleFunc("/health", h.handleHealth)
+	if reporter, ok := generator.(Reporter); ok {
+		h.mux.Handle("/metrics", NewMetricsHandler(reporter))
+	}
+	return h
+}
+
+// ServeHTTP dispatches the request to the matching endpoint
+// @param w - http.ResponseWriter to write the response to
+// @param r - *http.Request being served
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// handleID serves a single ID
+func (h *HTTPHandler) handleID(w http.ResponseWriter, r *http.Request) {
+	id, err := h.generator.GenerateContext(r.Context())
+	if err != nil {
+		writeError(w, r, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if wantsText(r) {
+		writeText(w, http.StatusOK, strconv.FormatInt(id, 10))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": strconv.FormatInt(id, 10)})
+}
+
+// handleIDs serves a batch of IDs
+func (h *HTTPHandler) handleIDs(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n < 1 || n > MaxBatchSize {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("n must be an integer between 1 and %d", MaxBatchSize))
+		return
+	}
+	ids, err := h.generator.GenerateNContext(r.Context(), n)
+	if err != nil {
+		writeError(w, r, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	formatted := make([]string, len(ids))
+	for i, id := range ids {
+		formatted[i] = strconv.FormatInt(id, 10)
+	}
+	if wantsText(r) {
+		writeText(w, http.StatusOK, strings.Join(formatted, "\n"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"ids": formatted})
+}
+
+// handleDecode serves the parts of an ID
+func (h *HTTPHandler) handleDecode(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("id")
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid id %q", raw))
+		return
+	}
+	c, err := h.generator.Decode(id)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	decoded := c.Describe(id)
+	if wantsText(r) {
+		writeText(w, http.StatusOK, fmt.Sprintf("id=%s\ntimestamp=%d\ntime=%s\ndatacenter_id=%d\nworker_id=%d\nsequence=%d",
+			decoded.ID, decoded.Timestamp, decoded.Time, decoded.DatacenterID, decoded.WorkerID, decoded.Sequence))
+		return
+	}
+	writeJSON(w, http.StatusOK, decoded)
+}
+
+// handleHealth reports whether the generator can currently issue IDs
+func (h *HTTPHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if err := h.generator.Health(r.Context()); err != nil {
+		writeError(w, r, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if wantsText(r) {
+		writeText(w, http.StatusOK, "ok")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// wantsText reports whether the client asked for plain text
+func wantsText(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "text":
+		return true
+	case "json":
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") && !strings.Contains(accept, "application/json")
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeText writes a plain-text response followed by a newline
+func writeText(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintln(w, body)
+}
+
+// writeError writes an error in the format the client asked for
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if wantsText(r) {
+		writeText(w, status, message)
+		return
+	}
+	writeJSON(w, status, map[string]string{"error": message})
+}
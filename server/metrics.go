@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// MetricsHandler Renders the statistics of a generator in the Prometheus text exposition format
+type MetricsHandler struct {
+	generator Reporter
+}
+
+// NewMetricsHandler Creates a Prometheus metrics handler for the generator
+// @param generator - Reporter whose statistics to expose, such as *snowflake.RedisSnowflake
+// @return *MetricsHandler - the created handler
+func NewMetricsHandler(generator Reporter) *MetricsHandler {
+	return &MetricsHandler{generator: generator}
+}
+
+// ServeHTTP writes the current metrics
+// @param w - http.ResponseWriter to write the metrics to
+// @param r - *http.Request being served
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	stats := h.generator.Stats()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b := bufio.NewWriter(w)
+	defer b.Flush()
+
+	fmt.Fprintf(b, "# HELP snowflake_info Node and exhaustion policy of the generator.\n# TYPE snowflake_info gauge\n")
+	fmt.Fprintf(b, "snowflake_info{datacenter_id=\"%d\",worker_id=\"%d\",exhaustion_policy=\"%s\"} 1\n",
+		stats.DatacenterID, stats.WorkerID, stats.ExhaustionPolicy)
+
+	writeMetric(b, "snowflake_ids_generated_total", "counter", "IDs issued.", float64(stats.Generated))
+	writeMetric(b, "snowflake_sequence_exhaustions_total", "counter", "Times the sequence of a millisecond was used up.", float64(stats.SequenceExhaustions))
+	writeMetric(b, "snowflake_sequence_exhaustion_wait_seconds_total", "counter", "Time spent waiting for the next millisecond after exhaustion.", stats.ExhaustionWait.Seconds())
+	writeMetric(b, "snowflake_clock_rollbacks_total", "counter", "Generations refused because the clock moved backwards.", float64(stats.ClockRollbacks))
+	writeMetric(b, "snowflake_strict_retries_total", "counter", "Strict-mode candidates already taken in Redis.", float64(stats.StrictRetries))
+	writeMetric(b, "snowflake_redis_errors_total", "counter", "Failed Redis calls.", float64(stats.RedisErrors))
+	writeHistogram(b, "snowflake_redis_latency_seconds", "Latency of Redis calls.", stats.RedisLatency)
+	writeMetric(b, "snowflake_clock_skew_seconds", "gauge", "Local clock minus Redis TIME at the last check.", stats.ClockSkew.Seconds())
+	writeMetric(b, "snowflake_lease_ttl_seconds", "gauge", "Remaining time of the worker slot lease.", stats.LeaseTTL.Seconds())
+}
+
+// writeMetric writes a single-sample metric with its HELP and TYPE lines
+func writeMetric(b *bufio.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, formatFloat(value))
+}
+
+// writeHistogram writes a histogram with cumulative buckets
+func writeHistogram(b *bufio.Writer, name, help string, h snowflake.LatencyHistogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(b, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(bound.Seconds()), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(b, "%s_sum %s\n", name, formatFloat(h.Sum.Seconds()))
+	fmt.Fprintf(b, "%s_count %d\n", name, h.Count)
+}
+
+// formatFloat renders a sample value in the shortest exact form
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
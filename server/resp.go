@@ -0,0 +1,430 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+const (
+	// maxPipeline is the largest number of buffered commands executed as one batch
+	maxPipeline = 1024
+	// maxArgs is the largest number of arguments a command may have
+	maxArgs = 16
+	// maxBulkLen is the largest bulk string a command argument may be
+	maxBulkLen = 4096
+)
+
+var (
+	// ErrServerClosed represents a Serve call returning because the server was closed
+	ErrServerClosed = errors.New("server: RESP server closed")
+	// errProtocol represents a request that is not valid RESP
+	errProtocol = errors.New("protocol error")
+)
+
+// replyEscaper removes line breaks that would end a simple string or error reply early
+var replyEscaper = strings.NewReplacer("\r", " ", "\n", " ")
+
+// RESPServer Serves IDs over a subset of the Redis protocol (RESP2), so any Redis client can fetch them
+//
+//	PING [message]  PONG, or the message
+//	NEXTID          one ID as an integer
+//	NEXTIDS n       an array of n IDs
+//	DECODE id       field/value array of the parts of an ID
+//	INFO            node, layout and runtime statistics
+//
+// IDs requested by pipelined NEXTID and NEXTIDS commands are generated together with GenerateNContext.
+type RESPServer struct {
+	generator Generator
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup // Tracks connection goroutines
+}
+
+// respCommand A parsed command and the number of IDs it asks for
+type respCommand struct {
+	name  string
+	args  []string
+	count int // IDs to generate, 0 for commands that do not generate or have invalid arguments
+}
+
+// NewRESPServer Creates a RESP server backed by the generator
+// @param generator - Generator generating the IDs; INFO reports statistics if it implements Reporter
+// @return *RESPServer - the created server
+func NewRESPServer(generator Generator) *RESPServer {
+	return &RESPServer{
+		generator: generator,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves connections until the server is closed
+// @param addr - string TCP address such as ":6390"
+// @return error - ErrServerClosed after Close, or any listen or accept error
+func (s *RESPServer) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on the listener until the server is closed
+// @param l - net.Listener to accept connections from, closed when Serve returns
+// @return error - ErrServerClosed after Close, or any accept error
+func (s *RESPServer) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		_ = l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners, closes open connections and waits for their handlers to return
+// @return error - always nil
+func (s *RESPServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		_ = l.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// serveConn reads pipelined batches of commands from a connection and answers them in order
+func (s *RESPServer) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		cmds, err := readBatch(r)
+		quit := s.execute(w, cmds)
+		if errors.Is(err, errProtocol) {
+			writeErrorReply(w, "ERR "+err.Error())
+		}
+		if flushErr := w.Flush(); flushErr != nil || err != nil || quit {
+			return
+		}
+	}
+}
+
+// readBatch reads one command and then every further command already buffered
+// @param r - *bufio.Reader of the connection
+// @return []respCommand - the commands read, possibly some even when an error is returned
+// @return error - errProtocol for malformed input, or any read error
+func readBatch(r *bufio.Reader) ([]respCommand, error) {
+	var cmds []respCommand
+	for len(cmds) == 0 || (r.Buffered() > 0 && len(cmds) < maxPipeline) {
+		args, err := readCommand(r)
+		if err != nil {
+			return cmds, err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		cmds = append(cmds, parseCommand(args))
+	}
+	return cmds, nil
+}
+
+// parseCommand normalizes the command name and works out how many IDs it asks for
+func parseCommand(args []string) respCommand {
+	cmd := respCommand{name: strings.ToUpper(args[0]), args: args[1:]}
+	switch {
+	case cmd.name == "NEXTID" && len(cmd.args) == 0:
+		cmd.count = 1
+	case cmd.name == "NEXTIDS" && len(cmd.args) == 1:
+		if n, err := strconv.Atoi(cmd.args[0]); err == nil && n >= 1 && n <= MaxBatchSize {
+			cmd.count = n
+		}
+	}
+	return cmd
+}
+
+// readCommand reads one command in multibulk or inline form
+// @param r - *bufio.Reader of the connection
+// @return []string - the command name followed by its arguments, empty for a blank line
+// @return error - errProtocol for malformed input, or any read error
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// Inline command, as typed into telnet
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	args := make([]string, 0, n)
+	for len(args) < n {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("%w: expected '$', got '%.1s'", errProtocol, header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 || size > maxBulkLen {
+			return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", errProtocol)
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+// readLine reads a line without its CRLF or LF terminator
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("%w: line too long", errProtocol)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
+
+// execute answers a batch of commands in order, generating the IDs of the whole batch together
+// @param w - *bufio.Writer of the connection
+// @param cmds - []respCommand to answer
+// @return bool - true if the client asked to close the connection
+func (s *RESPServer) execute(w *bufio.Writer, cmds []respCommand) bool {
+	pending := 0
+	for _, cmd := range cmds {
+		pending += cmd.count
+	}
+
+	var ids []int64
+	for _, cmd := range cmds {
+		if cmd.count > len(ids) {
+			// Refill for the rest of the batch, bounded so a long pipeline cannot hold the node lock for long
+			want := pending
+			if want > MaxBatchSize {
+				want = MaxBatchSize
+			}
+			if want < cmd.count {
+				want = cmd.count
+			}
+			more, err := s.generator.GenerateNContext(context.Background(), want-len(ids))
+			if err != nil {
+				pending -= cmd.count
+				writeErrorReply(w, "ERR "+err.Error())
+				continue
+			}
+			ids = append(ids, more...)
+		}
+		pending -= cmd.count
+
+		if cmd.name == "QUIT" {
+			writeSimpleString(w, "OK")
+			return true
+		}
+		s.dispatch(w, cmd, ids[:cmd.count])
+		ids = ids[cmd.count:]
+	}
+	return false
+}
+
+// dispatch answers a single command
+// @param w - *bufio.Writer of the connection
+// @param cmd - respCommand to answer
+// @param ids - []int64 generated for the command, as many as cmd.count
+func (s *RESPServer) dispatch(w *bufio.Writer, cmd respCommand, ids []int64) {
+	switch cmd.name {
+	case "PING":
+		switch len(cmd.args) {
+		case 0:
+			writeSimpleString(w, "PONG")
+		case 1:
+			writeBulkString(w, cmd.args[0])
+		default:
+			writeArityError(w, cmd.name)
+		}
+	case "NEXTID":
+		if len(cmd.args) != 0 {
+			writeArityError(w, cmd.name)
+			return
+		}
+		writeInteger(w, ids[0])
+	case "NEXTIDS":
+		if len(cmd.args) != 1 {
+			writeArityError(w, cmd.name)
+			return
+		}
+		if cmd.count == 0 {
+			writeErrorReply(w, fmt.Sprintf("ERR count must be an integer between 1 and %d", MaxBatchSize))
+			return
+		}
+		writeArrayHeader(w, len(ids))
+		for _, id := range ids {
+			writeInteger(w, id)
+		}
+	case "DECODE":
+		s.decode(w, cmd)
+	case "INFO":
+		writeBulkString(w, s.info())
+	case "COMMAND":
+		// redis-cli asks for command documentation on startup
+		writeArrayHeader(w, 0)
+	default:
+		writeErrorReply(w, fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(cmd.name)))
+	}
+}
+
+// decode answers DECODE with the parts of an ID as a field/value array
+func (s *RESPServer) decode(w *bufio.Writer, cmd respCommand) {
+	if len(cmd.args) != 1 {
+		writeArityError(w, cmd.name)
+		return
+	}
+	id, err := strconv.ParseInt(cmd.args[0], 10, 64)
+	if err != nil {
+		writeErrorReply(w, "ERR invalid id")
+		return
+	}
+	c, err := s.generator.Decode(id)
+	if err != nil {
+		writeErrorReply(w, "ERR "+err.Error())
+		return
+	}
+
+	writeArrayHeader(w, 10)
+	writeBulkString(w, "timestamp")
+	writeInteger(w, c.Timestamp)
+	writeBulkString(w, "time")
+	writeBulkString(w, c.Time.UTC().Format(snowflake.DecodedTimeFormat))
+	writeBulkString(w, "datacenter_id")
+	writeInteger(w, c.DatacenterID)
+	writeBulkString(w, "worker_id")
+	writeInteger(w, c.WorkerID)
+	writeBulkString(w, "sequence")
+	writeInteger(w, c.Sequence)
+}
+
+// info renders node, layout and runtime statistics in the INFO format of Redis
+// Generators that do not implement Reporter have empty sections
+func (s *RESPServer) info() string {
+	var b strings.Builder
+	b.WriteString("# Snowflake\r\n")
+	reporter, ok := s.generator.(Reporter)
+	if !ok {
+		b.WriteString("\r\n# Stats\r\n")
+		return b.String()
+	}
+	stats := reporter.Stats()
+	layout := reporter.Layout()
+	fmt.Fprintf(&b, "datacenter_id:%d\r\n", stats.DatacenterID)
+	fmt.Fprintf(&b, "worker_id:%d\r\n", stats.WorkerID)
+	fmt.Fprintf(&b, "epoch:%d\r\n", layout.Epoch)
+	fmt.Fprintf(&b, "timestamp_bits:%d\r\n", layout.TimestampBits())
+	fmt.Fprintf(&b, "datacenter_bits:%d\r\n", layout.DatacenterBits)
+	fmt.Fprintf(&b, "worker_bits:%d\r\n", layout.WorkerBits)
+	fmt.Fprintf(&b, "sequence_bits:%d\r\n", layout.SequenceBits)
+	b.WriteString("\r\n# Stats\r\n")
+	fmt.Fprintf(&b, "exhaustion_policy:%s\r\n", stats.ExhaustionPolicy)
+	fmt.Fprintf(&b, "sequence_exhaustions:%d\r\n", stats.SequenceExhaustions)
+	fmt.Fprintf(&b, "clock_skew_ms:%d\r\n", stats.ClockSkew.Milliseconds())
+	return b.String()
+}
+
+// writeSimpleString writes a +simple string reply
+func writeSimpleString(w *bufio.Writer, s string) {
+	_, _ = w.WriteString("+" + replyEscaper.Replace(s) + "\r\n")
+}
+
+// writeErrorReply writes a -error reply
+func writeErrorReply(w *bufio.Writer, message string) {
+	_, _ = w.WriteString("-" + replyEscaper.Replace(message) + "\r\n")
+}
+
+// writeArityError writes the error Redis returns for a wrong number of arguments
+func writeArityError(w *bufio.Writer, name string) {
+	writeErrorReply(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// writeInteger writes a :integer reply
+func writeInteger(w *bufio.Writer, n int64) {
+	_, _ = w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+// writeBulkString writes a $bulk string reply
+func writeBulkString(w *bufio.Writer, s string) {
+	_, _ = w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+// writeArrayHeader writes the *header of an array reply with n elements
+func writeArrayHeader(w *bufio.Writer, n int) {
+	_, _ = w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
@@ -0,0 +1,301 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+)
+
+// ErrCountersChanged represents allocation counters that moved between reading and resetting them
+var ErrCountersChanged = errors.New("counters changed since they were read")
+
+// adminScanCount is the number of keys asked for per SCAN call
+const adminScanCount = 100
+
+// adminClient Redis operations needed to inspect coordination state
+type adminClient interface {
+	redis.Client
+	redis.KeyValueClient
+	redis.ExpireClient
+	redis.ScanClient
+}
+
+// Slot A worker slot currently leased in Redis
+type Slot struct {
+	DatacenterID int64
+	WorkerID     int64
+	LeaseInfo                  // Holder and acquisition time; Holder is the raw value if it is not a LeaseInfo
+	TTL          time.Duration // Remaining lease time, -1 if the key has no expiration
+}
+
+// Admin Inspects and repairs the coordination keys in Redis
+type Admin struct {
+	client adminClient
+	layout Layout
+	clock  Clock
+	keys   keyspace
+}
+
+// NewAdmin Creates an Admin for the coordination keys in Redis
+// @param client - redis.Client that also implements redis.KeyValueClient, redis.ExpireClient and redis.ScanClient
+// @return *Admin - the created admin
+// @return error - ErrUnsupportedClient if the client lacks a required operation
+func NewAdmin(client redis.Client) (*Admin, error) {
+	c, ok := client.(adminClient)
+	if !ok {
+		return nil, fmt.Errorf("%w: admin operations require Get, Set, Expire, TTL and Scan", ErrUnsupportedClient)
+	}
+	return &Admin{client: c, layout: DefaultLayout, clock: systemClock{}}, nil
+}
+
+// SetLayout Sets the layout of the IDs stored under strict-mode keys
+// @param layout - Layout the generators use (default DefaultLayout)
+// @return *Admin - the admin instance for chaining
+func (a *Admin) SetLayout(layout Layout) *Admin {
+	a.layout = layout
+	return a
+}
+
+// SetKeyPrefix Sets the key prefix of the ID domain to administer
+// @param prefix - string key prefix the generators use (default DefaultKeyPrefix)
+// @return *Admin - the admin instance for chaining
+func (a *Admin) SetKeyPrefix(prefix string) *Admin {
+	a.keys = keyspace{prefix: prefix}
+	return a
+}
+
+// SetClock Sets the time source PurgeStrictKeys measures key age against
+// @param clock - Clock to read the current time from (default is the system clock)
+// @return *Admin - the admin instance for chaining
+func (a *Admin) SetClock(clock Clock) *Admin {
+	a.clock = clock
+	return a
+}
+
+// ListSlots Lists the worker slots currently leased in Redis
+// @param ctx - context for the operation
+// @return []Slot - the leased slots ordered by datacenter ID and worker ID
+// @return error - any error that occurred while talking to Redis
+func (a *Admin) ListSlots(ctx context.Context) ([]Slot, error) {
+	var slots []Slot
+	err := a.scan(ctx, a.keys.pattern("slot"), func(keys []string) error {
+		for _, key := range keys {
+			slot, ok, err := a.readSlot(ctx, key)
+			if err != nil {
+				return err
+			}
+			if ok {
+				slots = append(slots, slot)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].DatacenterID != slots[j].DatacenterID {
+			return slots[i].DatacenterID < slots[j].DatacenterID
+		}
+		return slots[i].WorkerID < slots[j].WorkerID
+	})
+	return slots, nil
+}
+
+// ForceRelease Deletes the lease of a worker slot whatever its holder, freeing a slot left by a crashed node
+// A holder that is still alive re-acquires the slot on its next renewal if nobody else took it
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return bool - true if the slot was leased
+// @return error - any error that occurred while talking to Redis
+func (a *Admin) ForceRelease(ctx context.Context, datacenterID, workerID int64) (bool, error) {
+	n, err := a.client.Del(ctx, a.keys.slot(datacenterID, workerID))
+	return n > 0, err
+}
+
+// ResetCounters Reports the allocation counters and deletes them when confirmed
+// Counter allocation restarts at the first slot afterwards, so only confirm once no node relies on
+// counter-allocated IDs without a lease; leased slots are protected by their slot keys
+// @param ctx - context for the operation
+// @param confirm - bool deleting the counters through DeleteCounters; false only reports them
+// @return map[string]int64 - the counter values before the reset, keyed by Redis key
+// @return error - ErrCountersChanged if a counter moved while resetting, or any error from Redis
+func (a *Admin) ResetCounters(ctx context.Context, confirm bool) (map[string]int64, error) {
+	counters := make(map[string]int64)
+	for _, key := range a.keys.counters() {
+		value, err := a.client.Get(ctx, key)
+		if errors.Is(err, redis.ErrNil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("counter %s holds %q: %w", key, value, err)
+		}
+		counters[key] = n
+	}
+
+	if confirm && len(counters) > 0 {
+		if err := a.DeleteCounters(ctx, counters); err != nil {
+			return counters, err
+		}
+	}
+	return counters, nil
+}
+
+// DeleteCounters Deletes the allocation counters that still hold the values reported by ResetCounters
+// Each counter is compared and deleted in one step, so a node allocating in between keeps its
+// counter instead of having it reset under it
+// @param ctx - context for the operation
+// @param counters - map[string]int64 the values that were reported, keyed by Redis key
+// @return error - ErrCountersChanged naming the counters left in place, ErrUnsupportedClient if the
+// client cannot compare and delete, or any other error from Redis
+func (a *Admin) DeleteCounters(ctx context.Context, counters map[string]int64) error {
+	client, ok := a.client.(redis.CompareAndDeleteClient)
+	if !ok {
+		return fmt.Errorf("%w: resetting counters requires CompareAndDelete", ErrUnsupportedClient)
+	}
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var changed []string
+	for _, key := range keys {
+		deleted, err := client.CompareAndDelete(ctx, key, strconv.FormatInt(counters[key], 10))
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w: %s", ErrCountersChanged, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// PurgeStrictKeys Scans the strict-mode <prefix>:id:* keys and deletes orphaned ones in batches
+// A key is orphaned when its ID is older than olderThan, since strict mode only guards the current
+// millisecond, or when it has no expiration and so would never go away on its own
+// @param ctx - context for the operation
+// @param olderThan - time.Duration after which the key of an ID is no longer needed
+// @param dryRun - bool only counting the orphaned keys
+// @return int64 - the number of keys deleted, or found when dryRun is set
+// @return error - any error that occurred while talking to Redis
+func (a *Admin) PurgeStrictKeys(ctx context.Context, olderThan time.Duration, dryRun bool) (int64, error) {
+	cutoff := millis(a.clock.Now().Add(-olderThan)) - a.layout.Epoch
+	var purged int64
+	err := a.scan(ctx, a.keys.pattern("id"), func(keys []string) error {
+		var orphans []string
+		for _, key := range keys {
+			orphaned, err := a.isOrphaned(ctx, key, cutoff)
+			if err != nil {
+				return err
+			}
+			if orphaned {
+				orphans = append(orphans, key)
+			}
+		}
+		if len(orphans) == 0 {
+			return nil
+		}
+		if dryRun {
+			purged += int64(len(orphans))
+			return nil
+		}
+		n, err := a.client.Del(ctx, orphans...)
+		purged += n
+		return err
+	})
+	return purged, err
+}
+
+// isOrphaned reports whether a strict-mode key is orphaned
+// @param ctx - context for the operation
+// @param key - string strict-mode key
+// @param cutoff - int64 layout timestamp before which IDs are no longer guarded
+// @return bool - true if the key can be deleted
+// @return error - any error that occurred while talking to Redis
+func (a *Admin) isOrphaned(ctx context.Context, key string, cutoff int64) (bool, error) {
+	id, ok := a.keys.parseID(key)
+	if !ok {
+		return false, nil
+	}
+	if id>>a.layout.timestampShift() < cutoff {
+		return true, nil
+	}
+	ttl, err := a.client.TTL(ctx, key)
+	if errors.Is(err, redis.ErrNil) {
+		return false, nil
+	}
+	return ttl < 0, err
+}
+
+// readSlot reads the lease stored under a slot key
+// @param ctx - context for the operation
+// @param key - string slot key
+// @return Slot - the slot
+// @return bool - false if the key is not a slot key or expired in the meantime
+// @return error - any error that occurred while talking to Redis
+func (a *Admin) readSlot(ctx context.Context, key string) (Slot, bool, error) {
+	var slot Slot
+	var ok bool
+	if slot.DatacenterID, slot.WorkerID, ok = a.keys.parseSlot(key); !ok {
+		return slot, false, nil
+	}
+
+	value, err := a.client.Get(ctx, key)
+	if errors.Is(err, redis.ErrNil) {
+		return slot, false, nil
+	}
+	if err != nil {
+		return slot, false, err
+	}
+	if json.Unmarshal([]byte(value), &slot.LeaseInfo) != nil {
+		slot.LeaseInfo = LeaseInfo{Holder: value}
+	}
+
+	slot.TTL, err = a.client.TTL(ctx, key)
+	if errors.Is(err, redis.ErrNil) {
+		return slot, false, nil
+	}
+	return slot, err == nil, err
+}
+
+// scan calls fn with every page of keys matching the pattern
+// @param ctx - context for the operation
+// @param match - string glob pattern
+// @param fn - func receiving each page, stopping the scan when it returns an error
+// @return error - any error returned by Redis or fn
+func (a *Admin) scan(ctx context.Context, match string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := a.client.Scan(ctx, cursor, match, adminScanCount)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,253 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+)
+
+// BackfillHolder is the lease holder of worker slots reserved for backfill
+const BackfillHolder = "backfill"
+
+const (
+	// backfillCheckInterval is how often a backfill generator confirms its reservation and claim
+	backfillCheckInterval = time.Second
+	// backfillClaimTTL is how long the claim of a generator that stopped checking keeps others off the slot
+	backfillClaimTTL = 30 * time.Second
+)
+
+var (
+	// ErrNotReserved represents a worker slot that is not reserved for backfill
+	ErrNotReserved = errors.New("worker slot is not reserved for backfill")
+	// ErrSlotUsed represents a backfill timestamp at or after the first live use of the worker slot
+	ErrSlotUsed = errors.New("worker slot was used by live nodes at this time")
+	// ErrBackfillExhausted represents a millisecond whose backfill sequence numbers are used up
+	ErrBackfillExhausted = errors.New("backfill sequence of the millisecond is used up")
+	// ErrBackfillClaimed represents a reserved worker slot another backfill generator is running on
+	ErrBackfillClaimed = errors.New("worker slot is claimed by another backfill generator")
+)
+
+// claimClient Redis operations needed to claim a reserved worker slot for one backfill generator
+type claimClient interface {
+	redis.CompareAndExpireClient
+	redis.CompareAndDeleteClient
+}
+
+// ReserveBackfill Reserves a worker slot for backfill with a lease that never expires
+// Leased nodes skip the slot while it is reserved; nodes that do not lease their slot are not kept off it
+// A slot leased before may still be reserved: backfill is then limited to times before its first live use
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return error - ErrSlotTaken if a live node holds the slot, or any Redis error
+func (a *Admin) ReserveBackfill(ctx context.Context, datacenterID, workerID int64) error {
+	if err := a.layout.validateNode(datacenterID, workerID); err != nil {
+		return err
+	}
+	value, err := json.Marshal(LeaseInfo{Holder: BackfillHolder, AcquiredAt: millis(a.clock.Now())})
+	if err != nil {
+		return err
+	}
+	ok, err := a.client.SetNX(ctx, a.keys.slot(datacenterID, workerID), string(value), 0)
+	if err != nil || ok {
+		return err
+	}
+	// Reserving again is a no-op
+	if err := a.checkBackfill(ctx, datacenterID, workerID); err != nil {
+		return fmt.Errorf("%w: %d/%d", ErrSlotTaken, datacenterID, workerID)
+	}
+	return nil
+}
+
+// UnreserveBackfill Returns a worker slot reserved for backfill to the live nodes
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return error - ErrNotReserved if the slot is not reserved, or any Redis error
+func (a *Admin) UnreserveBackfill(ctx context.Context, datacenterID, workerID int64) error {
+	if err := a.checkBackfill(ctx, datacenterID, workerID); err != nil {
+		return err
+	}
+	_, err := a.client.Del(ctx, a.keys.slot(datacenterID, workerID), a.keys.backfill(datacenterID, workerID))
+	return err
+}
+
+// PurgeBackfill Deletes the backfill records of a worker slot reserved for backfill: the first live lease
+// and the sequence counters of every backfilled millisecond
+// The slot is still reserved, so no live node can record a new first use meanwhile. Purge only once the
+// slot will not be backfilled again: a later backfill is bounded by the next live lease only and restarts
+// every sequence, so it may issue IDs that were issued before
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return int64 - the number of keys deleted
+// @return error - ErrNotReserved if the slot is not reserved, or any Redis error
+func (a *Admin) PurgeBackfill(ctx context.Context, datacenterID, workerID int64) (int64, error) {
+	if err := a.checkBackfill(ctx, datacenterID, workerID); err != nil {
+		return 0, err
+	}
+	purged, err := a.client.Del(ctx, a.keys.firstUse(datacenterID, workerID))
+	if err != nil {
+		return purged, err
+	}
+	err = a.scan(ctx, a.keys.backfillSequences(datacenterID, workerID), func(keys []string) error {
+		n, err := a.client.Del(ctx, keys...)
+		purged += n
+		return err
+	})
+	return purged, err
+}
+
+// checkBackfill checks that a worker slot is reserved for backfill
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return error - ErrNotReserved if the slot is free or leased by a live node, or any Redis error
+func (a *Admin) checkBackfill(ctx context.Context, datacenterID, workerID int64) error {
+	slot, ok, err := a.readSlot(ctx, a.keys.slot(datacenterID, workerID))
+	if err != nil {
+		return err
+	}
+	if !ok || slot.Holder != BackfillHolder || slot.TTL >= 0 {
+		return fmt.Errorf("%w: %d/%d", ErrNotReserved, datacenterID, workerID)
+	}
+	return nil
+}
+
+// BackfillGenerator Generates IDs with explicit timestamps on a worker slot reserved for backfill
+// The sequence of every millisecond is counted in Redis, so timestamps may come in any order and a restarted
+// backfill can continue on the same slot; the claim in Redis keeps a second generator off the slot
+type BackfillGenerator struct {
+	mu           sync.Mutex
+	admin        *Admin
+	client       claimClient
+	claim        string // Key claiming the slot for this generator
+	token        string // Value of the claim key while this generator holds it
+	layout       Layout
+	datacenterID int64
+	workerID     int64
+	liveFrom     int64     // Millisecond of the first live use of the slot, 0 if it was never leased
+	checkedAt    time.Time // Admin clock time the reservation was last confirmed
+}
+
+// Backfill Creates a backfill generator on a worker slot after checking its reservation in Redis
+// The generator claims the slot under <prefix>:backfill:<datacenter>:<worker>, so a second generator on
+// the same slot is refused until Close or, after a crash, until the claim expires after backfillClaimTTL.
+// It confirms the reservation and its claim again every backfillCheckInterval, so UnreserveBackfill stops it
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return *BackfillGenerator - the created generator using the admin's layout
+// @return error - ErrNotReserved if the slot is not reserved for backfill, ErrBackfillClaimed if another
+// generator runs on it, ErrUnsupportedClient if the client cannot compare and expire or delete, or any Redis error
+func (a *Admin) Backfill(ctx context.Context, datacenterID, workerID int64) (*BackfillGenerator, error) {
+	if err := a.layout.validateNode(datacenterID, workerID); err != nil {
+		return nil, err
+	}
+	client, ok := a.client.(claimClient)
+	if !ok {
+		return nil, fmt.Errorf("%w: backfill requires CompareAndExpire and CompareAndDelete", ErrUnsupportedClient)
+	}
+	g := &BackfillGenerator{
+		admin:        a,
+		client:       client,
+		claim:        a.keys.backfill(datacenterID, workerID),
+		token:        defaultLeaseHolder(),
+		layout:       a.layout,
+		datacenterID: datacenterID,
+		workerID:     workerID,
+	}
+	ok, err := a.client.SetNX(ctx, g.claim, g.token, backfillClaimTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %d/%d", ErrBackfillClaimed, datacenterID, workerID)
+	}
+	if err := g.check(ctx); err != nil {
+		_ = g.Close(ctx)
+		return nil, err
+	}
+	return g, nil
+}
+
+// Close Releases the claim of the generator on its slot, so another generator may continue the backfill
+// @param ctx - context for the operation
+// @return error - any error that occurred while talking to Redis
+func (g *BackfillGenerator) Close(ctx context.Context) error {
+	_, err := g.client.CompareAndDelete(ctx, g.claim, g.token)
+	return err
+}
+
+// GenerateAt Generates an ID whose timestamp is the millisecond of t
+// Timestamps may come in any order but must be before the first live use of the slot
+// @param t - time.Time the ID is created at
+// @return int64 - the generated ID
+// @return error - ErrTimestampOverflow if t is outside the layout's range, ErrSlotUsed if live nodes used
+// the slot at t, ErrBackfillExhausted if the millisecond is used up, ErrNotReserved once the reservation
+// is gone, ErrBackfillClaimed once the claim is lost, or any Redis error
+func (g *BackfillGenerator) GenerateAt(t time.Time) (int64, error) {
+	offset, err := g.layout.offsetAt(t)
+	if err != nil {
+		return 0, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ctx := context.Background()
+	if g.admin.clock.Now().Sub(g.checkedAt) >= backfillCheckInterval {
+		if err := g.check(ctx); err != nil {
+			return 0, err
+		}
+	}
+	timestamp := offset + g.layout.Epoch
+	if g.liveFrom > 0 && timestamp >= g.liveFrom {
+		return 0, fmt.Errorf("%w: %s", ErrSlotUsed, t.UTC().Format(time.RFC3339Nano))
+	}
+	n, err := g.admin.client.Incr(ctx, g.admin.keys.backfillSequence(g.datacenterID, g.workerID, timestamp))
+	if err != nil {
+		return 0, err
+	}
+	sequence := n - 1
+	if sequence > g.layout.MaxSequence() {
+		return 0, fmt.Errorf("%w: at %s", ErrBackfillExhausted, t.UTC().Format(time.RFC3339Nano))
+	}
+	return g.layout.compose(timestamp, g.datacenterID, g.workerID, sequence)
+}
+
+// check confirms the reservation, extends the claim and reloads the first live use of the slot
+// @param ctx - context for the operation
+// @return error - ErrNotReserved if the slot is no longer reserved, ErrBackfillClaimed if the claim
+// expired or was taken over, or any Redis error
+func (g *BackfillGenerator) check(ctx context.Context) error {
+	a := g.admin
+	if err := a.checkBackfill(ctx, g.datacenterID, g.workerID); err != nil {
+		return err
+	}
+	ok, err := g.client.CompareAndExpire(ctx, g.claim, g.token, backfillClaimTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %d/%d", ErrBackfillClaimed, g.datacenterID, g.workerID)
+	}
+	value, err := a.client.Get(ctx, a.keys.firstUse(g.datacenterID, g.workerID))
+	switch {
+	case errors.Is(err, redis.ErrNil):
+		g.liveFrom = 0
+	case err != nil:
+		return err
+	default:
+		if g.liveFrom, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("first use of slot %d/%d holds %q: %w", g.datacenterID, g.workerID, value, err)
+		}
+	}
+	g.checkedAt = a.clock.Now()
+	return nil
+}
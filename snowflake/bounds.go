@@ -0,0 +1,107 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidRange represents a time range whose end is before its start
+var ErrInvalidRange = errors.New("invalid time range")
+
+// MinTime Returns the earliest time the layout can represent
+// @return time.Time - the epoch of the layout
+func (l Layout) MinTime() time.Time {
+	return time.UnixMilli(l.Epoch)
+}
+
+// MaxTime Returns the last millisecond the layout can represent
+// @return time.Time - the epoch plus the maximum timestamp
+func (l Layout) MaxTime() time.Time {
+	return time.UnixMilli(l.Epoch + l.MaxTimestamp())
+}
+
+// offsetAt returns the timestamp offset of the millisecond containing t
+// @param t - time.Time to convert
+// @return int64 - milliseconds since Epoch
+// @return error - ErrTimestampOverflow if t is outside the layout's range
+func (l Layout) offsetAt(t time.Time) (int64, error) {
+	if t.Before(l.MinTime()) || t.After(l.MaxTime().Add(time.Millisecond-1)) {
+		return 0, fmt.Errorf("%w: %s is outside %s to %s", ErrTimestampOverflow,
+			t.UTC().Format(time.RFC3339Nano), l.MinTime().UTC().Format(time.RFC3339Nano), l.MaxTime().UTC().Format(time.RFC3339Nano))
+	}
+	return t.UnixMilli() - l.Epoch, nil
+}
+
+// MinIDAt Returns the smallest ID the layout can produce in the millisecond of t
+// @param t - time.Time within the millisecond
+// @return int64 - the smallest ID, with all node and sequence bits 0
+// @return error - ErrTimestampOverflow if t is outside the layout's range
+func (l Layout) MinIDAt(t time.Time) (int64, error) {
+	offset, err := l.offsetAt(t)
+	if err != nil {
+		return 0, err
+	}
+	return offset << l.timestampShift(), nil
+}
+
+// MaxIDAt Returns the largest ID the layout can produce in the millisecond of t
+// @param t - time.Time within the millisecond
+// @return int64 - the largest ID, with all node and sequence bits 1
+// @return error - ErrTimestampOverflow if t is outside the layout's range
+func (l Layout) MaxIDAt(t time.Time) (int64, error) {
+	offset, err := l.offsetAt(t)
+	if err != nil {
+		return 0, err
+	}
+	return offset<<l.timestampShift() | (1<<l.timestampShift() - 1), nil
+}
+
+// RangeFor Returns the ID bounds of a time range for queries such as id BETWEEN min AND max
+// Both bounds are inclusive: min is the smallest ID of the start millisecond and max the largest ID of the end millisecond
+// @param start - time.Time of the first millisecond
+// @param end - time.Time of the last millisecond
+// @return int64 - the smallest ID of the range
+// @return int64 - the largest ID of the range
+// @return error - ErrInvalidRange if end is before start, ErrTimestampOverflow if either is outside the layout's range
+func (l Layout) RangeFor(start, end time.Time) (int64, int64, error) {
+	if end.Before(start) {
+		return 0, 0, fmt.Errorf("%w: end %s is before start %s", ErrInvalidRange,
+			end.UTC().Format(time.RFC3339Nano), start.UTC().Format(time.RFC3339Nano))
+	}
+	minID, err := l.MinIDAt(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxID, err := l.MaxIDAt(end)
+	if err != nil {
+		return 0, 0, err
+	}
+	return minID, maxID, nil
+}
+
+// MinIDAt Returns the smallest ID of the instance's layout in the millisecond of t
+// @param t - time.Time within the millisecond
+// @return int64 - the smallest ID
+// @return error - ErrTimestampOverflow if t is outside the layout's range
+func (rs *RedisSnowflake) MinIDAt(t time.Time) (int64, error) {
+	return rs.layout.MinIDAt(t)
+}
+
+// MaxIDAt Returns the largest ID of the instance's layout in the millisecond of t
+// @param t - time.Time within the millisecond
+// @return int64 - the largest ID
+// @return error - ErrTimestampOverflow if t is outside the layout's range
+func (rs *RedisSnowflake) MaxIDAt(t time.Time) (int64, error) {
+	return rs.layout.MaxIDAt(t)
+}
+
+// RangeFor Returns the inclusive ID bounds of a time range in the instance's layout
+// @param start - time.Time of the first millisecond
+// @param end - time.Time of the last millisecond
+// @return int64 - the smallest ID of the range
+// @return int64 - the largest ID of the range
+// @return error - ErrInvalidRange or ErrTimestampOverflow
+func (rs *RedisSnowflake) RangeFor(start, end time.Time) (int64, int64, error) {
+	return rs.layout.RangeFor(start, end)
+}
@@ -0,0 +1,65 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrLifetimeTooShort represents a layout and epoch that run out of timestamps too soon
+var ErrLifetimeTooShort = errors.New("layout lifetime too short")
+
+// Capacity Limits of a layout
+type Capacity struct {
+	ExhaustsAt       time.Time     // First millisecond the layout cannot represent, IDs are refused from then on
+	Remaining        time.Duration // Time left until ExhaustsAt, 0 once exhausted
+	MaxNodes         int64         // Number of nodes that can generate at the same time
+	PeakIDsPerSecond int64         // Most IDs one node can issue per second
+}
+
+// Capacity Reports when the layout runs out of timestamps and how much it can issue
+// @param now - time.Time to measure the remaining lifetime from
+// @return Capacity - the limits of the layout
+func (l Layout) Capacity(now time.Time) Capacity {
+	exhaustsAt := l.Epoch + l.MaxTimestamp() + 1
+	return Capacity{
+		ExhaustsAt:       time.UnixMilli(exhaustsAt),
+		Remaining:        millisDuration(exhaustsAt - now.UnixMilli()),
+		MaxNodes:         l.maxNode() + 1,
+		PeakIDsPerSecond: (l.MaxSequence() + 1) * 1000,
+	}
+}
+
+// Capacity Reports the limits of the instance's layout, measuring the remaining lifetime from its clock
+// @return Capacity - the limits of the layout
+func (rs *RedisSnowflake) Capacity() Capacity {
+	return rs.layout.Capacity(rs.clock.Now())
+}
+
+// checkLifetime refuses a layout whose timestamps run out within the minimum lifetime
+// @param now - time.Time to measure the remaining lifetime from
+// @param minLifetime - time.Duration the layout must still last, 0 to skip the check
+// @return error - ErrLifetimeTooShort describing the exhaustion date
+func (l Layout) checkLifetime(now time.Time, minLifetime time.Duration) error {
+	if minLifetime <= 0 {
+		return nil
+	}
+	capacity := l.Capacity(now)
+	if capacity.Remaining < minLifetime {
+		return fmt.Errorf("%w: IDs run out at %s, in %s, less than %s", ErrLifetimeTooShort,
+			capacity.ExhaustsAt.UTC().Format(time.RFC3339), capacity.Remaining.Round(time.Second), minLifetime)
+	}
+	return nil
+}
+
+// millisDuration converts milliseconds to a duration, clamped to 0 and the largest duration
+func millisDuration(ms int64) time.Duration {
+	if ms <= 0 {
+		return 0
+	}
+	if ms > math.MaxInt64/int64(time.Millisecond) {
+		return math.MaxInt64
+	}
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,106 @@
+package snowflake
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dammPolynomial is x^5 + x^2 + 1, the primitive polynomial defining GF(32) for the check character
+const dammPolynomial = 0x25
+
+// CheckedEncoding Crockford base32 with a trailing Damm check character, for IDs typed or read out by people
+// Decoding detects every single-character error and every transposition of adjacent characters
+type CheckedEncoding struct {
+	groupSize int
+}
+
+// Checked32 encodes IDs as 13 Crockford base32 characters plus a check character, without grouping
+var Checked32 = NewCheckedEncoding(0)
+
+// NewCheckedEncoding Creates a checked encoding that separates groups of characters with dashes
+// @param groupSize - int characters per group counted from the left, 0 for no dashes
+// @return *CheckedEncoding - the created encoding
+func NewCheckedEncoding(groupSize int) *CheckedEncoding {
+	if groupSize < 0 {
+		groupSize = 0
+	}
+	return &CheckedEncoding{groupSize: groupSize}
+}
+
+// dammStep combines the interim check digit with the next digit, interim*x + digit in GF(32)
+// The operation is a totally anti-symmetric quasigroup, which is what the error detection relies on
+// @param interim - byte interim check digit
+// @param digit - byte next digit
+// @return byte - the new interim check digit
+func dammStep(interim, digit byte) byte {
+	doubled := interim << 1
+	if doubled&0x20 != 0 {
+		doubled ^= dammPolynomial
+	}
+	return doubled ^ digit
+}
+
+// Encode Returns the Crockford base32 form of the ID followed by its check character
+// @param id - ID to encode, expected to be non-negative
+// @return string - the encoded ID, grouped with dashes if configured
+func (e *CheckedEncoding) Encode(id ID) string {
+	body := Crockford32.Encode(id)
+	var interim byte
+	for i := 0; i < len(body); i++ {
+		interim = dammStep(interim, Crockford32.decode[body[i]]-1)
+	}
+	// The check digit brings the interim digit back to 0
+	check := dammStep(interim, 0)
+	return e.group(body + string(Crockford32.alphabet[check]))
+}
+
+// group inserts a dash after every groupSize characters
+// @param s - string ungrouped form
+// @return string - the grouped form
+func (e *CheckedEncoding) group(s string) string {
+	if e.groupSize == 0 || len(s) <= e.groupSize {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i += e.groupSize {
+		if i > 0 {
+			b.WriteByte('-')
+		}
+		end := i + e.groupSize
+		if end > len(s) {
+			end = len(s)
+		}
+		b.WriteString(s[i:end])
+	}
+	return b.String()
+}
+
+// Decode Parses an encoded ID after verifying its check character
+// Case, dashes and omitted leading zeros are accepted, and I, L and O are read as 1, 1 and 0
+// @param s - string encoded ID
+// @return ID - the decoded ID
+// @return error - ErrInvalidID if s is malformed or the check character does not match
+func (e *CheckedEncoding) Decode(s string) (ID, error) {
+	compact := strings.ReplaceAll(s, "-", "")
+	if len(compact) < 2 || len(compact) > Crockford32.width+1 {
+		return 0, fmt.Errorf("%w: %q must have 2 to %d characters", ErrInvalidID, s, Crockford32.width+1)
+	}
+	var interim byte
+	for i := 0; i < len(compact); i++ {
+		digit := Crockford32.decode[compact[i]]
+		if digit == 0 {
+			return 0, fmt.Errorf("%w: invalid character %q in %q", ErrInvalidID, compact[i], s)
+		}
+		interim = dammStep(interim, digit-1)
+	}
+	if interim != 0 {
+		return 0, fmt.Errorf("%w: check character mismatch in %q", ErrInvalidID, s)
+	}
+	return Crockford32.Decode(compact[:len(compact)-1])
+}
+
+// Checked32 Returns the ID encoded with Checked32
+// @return string - the 14-character form
+func (id ID) Checked32() string {
+	return Checked32.Encode(id)
+}
@@ -0,0 +1,32 @@
+package snowflake
+
+import "time"
+
+// Clock Source of the current time, replaceable for deterministic tests
+type Clock interface {
+	// Now returns the current time
+	// @return time.Time - the current time
+	Now() time.Time
+}
+
+// systemClock Clock backed by time.Now
+type systemClock struct{}
+
+// Now returns the current system time
+// @return time.Time - the current time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// millis converts a time to milliseconds since the Unix epoch
+// @param t - time.Time to convert
+// @return int64 - milliseconds since the Unix epoch
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// currentTimeMillis Gets current timestamp in milliseconds from the instance clock
+// @return int64 - current timestamp in milliseconds
+func (rs *RedisSnowflake) currentTimeMillis() int64 {
+	return millis(rs.clock.Now())
+}
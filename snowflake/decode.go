@@ -0,0 +1,87 @@
+package snowflake
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// DecodedTimeFormat renders the time of a DecodedID in UTC with millisecond precision
+const DecodedTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// ErrInvalidID represents an ID that cannot have been generated (e.g. negative)
+var ErrInvalidID = errors.New("invalid ID")
+
+// Components Parts of a decoded ID
+type Components struct {
+	Timestamp    int64     // Milliseconds since the Unix epoch
+	Time         time.Time // Timestamp as a time
+	DatacenterID int64     // Datacenter ID
+	WorkerID     int64     // Worker ID
+	Sequence     int64     // Sequence number within the millisecond
+}
+
+// DecodedID JSON form of a decoded ID, shared by the HTTP server and the CLI
+// The ID is a string because it exceeds the safe integer range of JavaScript
+type DecodedID struct {
+	ID           string `json:"id"`
+	Timestamp    int64  `json:"timestamp"`
+	Time         string `json:"time"` // Formatted with DecodedTimeFormat
+	DatacenterID int64  `json:"datacenter_id"`
+	WorkerID     int64  `json:"worker_id"`
+	Sequence     int64  `json:"sequence"`
+}
+
+// Describe Returns the JSON form of the parts of an ID
+// @param id - int64 ID the parts were decoded from
+// @return DecodedID - the ID and its parts with the time formatted
+func (c Components) Describe(id int64) DecodedID {
+	return DecodedID{
+		ID:           strconv.FormatInt(id, 10),
+		Timestamp:    c.Timestamp,
+		Time:         c.Time.UTC().Format(DecodedTimeFormat),
+		DatacenterID: c.DatacenterID,
+		WorkerID:     c.WorkerID,
+		Sequence:     c.Sequence,
+	}
+}
+
+// Decode Splits an ID into its parts using the layout
+// @param id - int64 ID to decode
+// @return Components - the decoded parts
+// @return error - ErrInvalidID if the ID is negative
+func (l Layout) Decode(id int64) (Components, error) {
+	if id < 0 {
+		return Components{}, ErrInvalidID
+	}
+	timestamp := (id >> l.timestampShift()) + l.Epoch
+	return Components{
+		Timestamp:    timestamp,
+		Time:         time.Unix(0, timestamp*int64(time.Millisecond)),
+		DatacenterID: (id >> l.datacenterShift()) & l.MaxDatacenterID(),
+		WorkerID:     (id >> l.workerShift()) & l.MaxWorkerID(),
+		Sequence:     id & l.MaxSequence(),
+	}, nil
+}
+
+// Decode Splits an ID generated with the default layout into its parts
+// @param id - int64 ID to decode
+// @return Components - the decoded parts
+// @return error - ErrInvalidID if the ID is negative
+func Decode(id int64) (Components, error) {
+	return DefaultLayout.Decode(id)
+}
+
+// Decode Splits an ID generated by this instance into its parts
+// @param id - int64 ID to decode
+// @return Components - the decoded parts
+// @return error - ErrInvalidID if the ID is negative
+func (rs *RedisSnowflake) Decode(id int64) (Components, error) {
+	return rs.layout.Decode(id)
+}
+
+// Layout Returns the layout this instance generates IDs with
+// @return Layout - the layout including its epoch
+func (rs *RedisSnowflake) Layout() Layout {
+	return rs.layout
+}
@@ -0,0 +1,127 @@
+package snowflake
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Encoding Fixed-width string form of IDs over an ASCII-sorted alphabet
+// Encoded IDs sort lexicographically in numeric order
+type Encoding struct {
+	alphabet      string
+	width         int       // Characters needed for any uint64
+	decode        [256]byte // Digit value plus one for each accepted byte, 0 for invalid bytes
+	ignoreHyphens bool
+}
+
+var (
+	// Base62 encodes IDs in 11 characters of 0-9, A-Z and a-z
+	Base62 = newEncoding("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", false)
+	// Base58 encodes IDs in 11 characters of the Bitcoin alphabet, without 0, O, I and l
+	Base58 = newEncoding("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz", false)
+	// Crockford32 encodes IDs in 13 characters of Crockford's base32; decoding ignores case and hyphens
+	// and reads I and L as 1 and O as 0
+	Crockford32 = newEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ", true).
+			alias("abcdefghjkmnpqrstvwxyz", "ABCDEFGHJKMNPQRSTVWXYZ").
+			alias("IiLlOo", "111100")
+	// Hex encodes IDs in 16 lowercase hexadecimal digits; decoding ignores case
+	Hex = newEncoding("0123456789abcdef", false).alias("ABCDEF", "abcdef")
+)
+
+// newEncoding creates an encoding wide enough for any uint64
+// @param alphabet - string digits in ascending ASCII order
+// @param ignoreHyphens - bool whether decoding skips '-' separators
+// @return *Encoding - the created encoding
+func newEncoding(alphabet string, ignoreHyphens bool) *Encoding {
+	e := &Encoding{alphabet: alphabet, ignoreHyphens: ignoreHyphens}
+	for i := 0; i < len(alphabet); i++ {
+		e.decode[alphabet[i]] = byte(i + 1)
+	}
+	base := uint64(len(alphabet))
+	for n := uint64(math.MaxUint64); n > 0; n /= base {
+		e.width++
+	}
+	return e
+}
+
+// alias makes each byte of from decode like the byte at the same position of to
+// @param from - string extra accepted bytes
+// @param to - string alphabet bytes they stand for
+// @return *Encoding - the encoding for chaining
+func (e *Encoding) alias(from, to string) *Encoding {
+	for i := 0; i < len(from); i++ {
+		e.decode[from[i]] = e.decode[to[i]]
+	}
+	return e
+}
+
+// Width Returns the length of every encoded ID
+// @return int - number of characters
+func (e *Encoding) Width() int {
+	return e.width
+}
+
+// Encode Returns the fixed-width form of the ID
+// @param id - ID to encode, expected to be non-negative
+// @return string - the encoded ID, zero-padded to Width characters
+func (e *Encoding) Encode(id ID) string {
+	buf := make([]byte, e.width)
+	base := uint64(len(e.alphabet))
+	n := uint64(id)
+	for i := e.width - 1; i >= 0; i-- {
+		buf[i] = e.alphabet[n%base]
+		n /= base
+	}
+	return string(buf)
+}
+
+// Decode Parses an encoded ID; leading zero digits may be omitted
+// @param s - string encoded ID
+// @return ID - the decoded ID
+// @return error - ErrInvalidID if s is empty, too long, contains an invalid character or exceeds the int64 range
+func (e *Encoding) Decode(s string) (ID, error) {
+	if e.ignoreHyphens {
+		s = strings.ReplaceAll(s, "-", "")
+	}
+	if s == "" || len(s) > e.width {
+		return 0, fmt.Errorf("%w: %q must have 1 to %d characters", ErrInvalidID, s, e.width)
+	}
+	base := uint64(len(e.alphabet))
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		digit := e.decode[s[i]]
+		if digit == 0 {
+			return 0, fmt.Errorf("%w: invalid character %q in %q", ErrInvalidID, s[i], s)
+		}
+		if n > (math.MaxInt64-uint64(digit-1))/base {
+			return 0, fmt.Errorf("%w: %q overflows int64", ErrInvalidID, s)
+		}
+		n = n*base + uint64(digit-1)
+	}
+	return ID(n), nil
+}
+
+// Base62 Returns the ID encoded with Base62
+// @return string - the 11-character form
+func (id ID) Base62() string {
+	return Base62.Encode(id)
+}
+
+// Base58 Returns the ID encoded with Base58
+// @return string - the 11-character form
+func (id ID) Base58() string {
+	return Base58.Encode(id)
+}
+
+// Crockford32 Returns the ID encoded with Crockford32
+// @return string - the 13-character form
+func (id ID) Crockford32() string {
+	return Crockford32.Encode(id)
+}
+
+// Hex Returns the ID encoded with Hex
+// @return string - the 16-character form
+func (id ID) Hex() string {
+	return Hex.Encode(id)
+}
@@ -0,0 +1,95 @@
+package snowflake
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultBorrowLimit is how far ahead of the clock ExhaustionBorrow may run
+const DefaultBorrowLimit = 100 * time.Millisecond
+
+// ExhaustionPolicy Decides what happens when all sequence numbers of a millisecond are used up
+type ExhaustionPolicy int
+
+const (
+	// ExhaustionSpin busy-spins on the clock until the next millisecond (default)
+	ExhaustionSpin ExhaustionPolicy = iota
+	// ExhaustionSleep sleeps until the next millisecond
+	ExhaustionSleep
+	// ExhaustionError returns ErrOverFlow immediately
+	ExhaustionError
+	// ExhaustionBorrow uses future milliseconds up to the borrow limit, then sleeps until the clock catches up
+	ExhaustionBorrow
+)
+
+// String Returns the name of the policy
+// @return string - the policy name
+func (p ExhaustionPolicy) String() string {
+	switch p {
+	case ExhaustionSpin:
+		return "spin"
+	case ExhaustionSleep:
+		return "sleep"
+	case ExhaustionError:
+		return "error"
+	case ExhaustionBorrow:
+		return "borrow"
+	default:
+		return fmt.Sprintf("ExhaustionPolicy(%d)", int(p))
+	}
+}
+
+// nextMillis returns the millisecond to continue in once the sequence of last is exhausted
+// Must be called with the node lock held
+// @param last - int64 the exhausted millisecond
+// @return int64 - a millisecond after last
+// @return error - ErrOverFlow under ExhaustionError
+func (rs *RedisSnowflake) nextMillis(last int64) (int64, error) {
+	rs.sequenceExhaustions.Add(1)
+	if rs.exhaustionPolicy == ExhaustionError {
+		rs.emit(func(o Observer) { o.OnSequenceExhausted(0) })
+		return 0, ErrOverFlow
+	}
+
+	start := time.Now()
+	defer func() {
+		wait := time.Since(start)
+		rs.metrics.exhaustionWait.Add(int64(wait))
+		rs.emit(func(o Observer) { o.OnSequenceExhausted(wait) })
+	}()
+	switch rs.exhaustionPolicy {
+	case ExhaustionSleep:
+		return rs.sleepUntil(last + 1), nil
+	case ExhaustionBorrow:
+		next := last + 1
+		// Beyond the limit, wait until the borrowed millisecond is within reach again
+		rs.sleepUntil(next - rs.borrowLimit.Milliseconds())
+		return next, nil
+	default:
+		timestamp := rs.currentTimeMillis()
+		for timestamp <= last {
+			timestamp = rs.currentTimeMillis()
+		}
+		return timestamp, nil
+	}
+}
+
+// sleepUntil sleeps until the clock reaches the given millisecond
+// @param target - int64 millisecond to wait for
+// @return int64 - the current millisecond, at least target
+func (rs *RedisSnowflake) sleepUntil(target int64) int64 {
+	timestamp := rs.currentTimeMillis()
+	for timestamp < target {
+		time.Sleep(time.Duration(target-timestamp) * time.Millisecond)
+		timestamp = rs.currentTimeMillis()
+	}
+	return timestamp
+}
+
+// withinBorrowed reports whether a clock behind the last timestamp is explained by borrowing
+// @param timestamp - int64 current millisecond
+// @param last - int64 last issued millisecond
+// @return bool - true if the gap is within the borrow limit under ExhaustionBorrow
+func (rs *RedisSnowflake) withinBorrowed(timestamp, last int64) bool {
+	return rs.exhaustionPolicy == ExhaustionBorrow && last-timestamp <= rs.borrowLimit.Milliseconds()
+}
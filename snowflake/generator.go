@@ -0,0 +1,98 @@
+package snowflake
+
+import (
+	"context"
+	"sync"
+)
+
+// IDGenerator Interface implemented by every generator in this package, so services can swap implementations
+type IDGenerator interface {
+	// Generate returns a unique ID
+	// @return int64 - the generated ID
+	// @return error - any error that occurred during generation
+	Generate() (int64, error)
+	// GenerateContext returns a unique ID, giving up when ctx is done
+	// @param ctx - context for the operation
+	// @return int64 - the generated ID
+	// @return error - any error that occurred during generation
+	GenerateContext(ctx context.Context) (int64, error)
+	// GenerateN returns n unique IDs in generation order
+	// @param n - int number of IDs to generate
+	// @return []int64 - the generated IDs
+	// @return error - any error that occurred during generation
+	GenerateN(n int) ([]int64, error)
+	// Close releases the resources of the generator, after which generation fails with ErrClosed
+	// @return error - any error that occurred while closing
+	Close() error
+}
+
+var (
+	_ IDGenerator = (*RedisSnowflake)(nil)
+	_ IDGenerator = (*SequentialGenerator)(nil)
+)
+
+// SequentialGenerator Deterministic IDGenerator for tests, returning consecutive integers
+type SequentialGenerator struct {
+	mu     sync.Mutex
+	next   int64
+	closed bool
+}
+
+// NewSequentialGenerator Creates a generator whose first ID is start
+// @param start - int64 first ID to return
+// @return *SequentialGenerator - the created generator
+func NewSequentialGenerator(start int64) *SequentialGenerator {
+	return &SequentialGenerator{next: start}
+}
+
+// Generate Returns the next integer
+// @return int64 - the generated ID
+// @return error - ErrClosed after Close
+func (g *SequentialGenerator) Generate() (int64, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext Returns the next integer
+// @param ctx - context for the operation
+// @return int64 - the generated ID
+// @return error - the context error if ctx is done, or ErrClosed after Close
+func (g *SequentialGenerator) GenerateContext(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	ids, err := g.GenerateN(1)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// GenerateN Returns the next n integers
+// @param n - int number of IDs to generate
+// @return []int64 - the generated IDs
+// @return error - ErrClosed after Close
+func (g *SequentialGenerator) GenerateN(n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return nil, ErrClosed
+	}
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = g.next
+		g.next++
+	}
+	return ids, nil
+}
+
+// Close Stops the generator
+// @return error - always nil
+func (g *SequentialGenerator) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	return nil
+}
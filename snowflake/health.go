@@ -0,0 +1,75 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+)
+
+var (
+	// ErrClosed represents a generator that has been closed
+	ErrClosed = errors.New("generator is closed")
+	// ErrRedisUnavailable represents a Redis server that cannot be reached
+	ErrRedisUnavailable = errors.New("redis is unavailable")
+	// ErrLeaseExpired represents a worker slot lease that has run out
+	ErrLeaseExpired = errors.New("worker slot lease has expired")
+	// ErrLeaseExpiring represents a lease with less than a third of its TTL left, so renewals are failing
+	ErrLeaseExpiring = errors.New("worker slot lease is about to expire")
+)
+
+// Health Reports whether the generator is safe to serve, for readiness probes
+// The checks run in order: closed, clock skew, Redis reachability, then lease validity
+// @param ctx - context bounding the Redis calls
+// @return error - nil when healthy, otherwise wrapping ErrClosed, ErrClockSkew, ErrRedisUnavailable,
+// ErrLeaseExpired, ErrLeaseExpiring or ErrSlotTaken
+func (rs *RedisSnowflake) Health(ctx context.Context) error {
+	if rs.closed.Load() {
+		return ErrClosed
+	}
+	if rs.skewExceeded.Load() {
+		return fmt.Errorf("%w: %v", ErrClockSkew, time.Duration(rs.clockSkew.Load()))
+	}
+	if pinger, ok := rs.redisClient.(redis.PingClient); ok {
+		start := time.Now()
+		err := pinger.Ping(ctx)
+		rs.metrics.observeRedis(start, err)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRedisUnavailable, err)
+		}
+	}
+	if l := rs.lease.Load(); l != nil {
+		return l.check(ctx, rs.currentTimeMillis())
+	}
+	return nil
+}
+
+// check verifies that the lease has time left and is still held by this process
+// @param ctx - context for the operation
+// @param now - int64 current local milliseconds
+// @return error - ErrLeaseExpired, ErrLeaseExpiring, ErrSlotTaken or ErrRedisUnavailable
+func (l *lease) check(ctx context.Context, now int64) error {
+	remaining := time.Duration(l.expiresAt.Load()-now) * time.Millisecond
+	if remaining <= 0 {
+		return fmt.Errorf("%w: %s", ErrLeaseExpired, l.key)
+	}
+	if remaining < l.ttl/3 {
+		return fmt.Errorf("%w: %s has %v left", ErrLeaseExpiring, l.key, remaining)
+	}
+
+	start := time.Now()
+	value, err := l.client.Get(ctx, l.key)
+	l.metrics.observeRedis(start, ignoreNil(err))
+	if errors.Is(err, redis.ErrNil) {
+		return fmt.Errorf("%w: %s no longer exists", ErrLeaseExpired, l.key)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRedisUnavailable, err)
+	}
+	if value != l.value {
+		return fmt.Errorf("%w: %s", ErrSlotTaken, l.key)
+	}
+	return nil
+}
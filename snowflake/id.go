@@ -0,0 +1,146 @@
+package snowflake
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ID Generated ID that marshals to JSON as a string, so clients limited to 2^53 keep every digit
+// It also implements encoding.TextMarshaler, sql.Scanner and driver.Valuer
+type ID int64
+
+// ParseID Parses the decimal form of an ID
+// @param s - string decimal ID
+// @return ID - the parsed ID
+// @return error - ErrInvalidID if s is not a non-negative decimal integer
+func ParseID(s string) (ID, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, s)
+	}
+	return ID(n), nil
+}
+
+// Int64 Returns the ID as an int64
+// @return int64 - the ID
+func (id ID) Int64() int64 {
+	return int64(id)
+}
+
+// String Returns the decimal form of the ID
+// @return string - the decimal ID
+func (id ID) String() string {
+	return strconv.FormatInt(int64(id), 10)
+}
+
+// Decode Splits the ID into its parts using DefaultLayout, see Layout.Decode for other layouts
+// @return Components - the decoded parts
+// @return error - ErrInvalidID if the ID is negative
+func (id ID) Decode() (Components, error) {
+	return DefaultLayout.Decode(int64(id))
+}
+
+// Time Returns the time the ID was generated at under DefaultLayout
+// @return time.Time - the generation time, zero if the ID is negative
+func (id ID) Time() time.Time {
+	c, err := id.Decode()
+	if err != nil {
+		return time.Time{}
+	}
+	return c.Time
+}
+
+// MarshalJSON Encodes the ID as a JSON string
+// @return []byte - the quoted decimal ID
+// @return error - always nil
+func (id ID) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(id.String())), nil
+}
+
+// UnmarshalJSON Decodes an ID from a JSON string or number; null leaves the ID unchanged
+// @param data - []byte JSON value
+// @return error - ErrInvalidID if the value is not an ID
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	return id.UnmarshalText(data)
+}
+
+// MarshalText Encodes the ID in decimal
+// @return []byte - the decimal ID
+// @return error - always nil
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText Decodes a decimal ID
+// @param text - []byte decimal ID
+// @return error - ErrInvalidID if text is not an ID
+func (id *ID) UnmarshalText(text []byte) error {
+	parsed, err := ParseID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// Scan Reads an ID from an integer, string or byte column
+// @param src - interface{} value returned by the database driver
+// @return error - ErrInvalidID for NULL or values that are not IDs
+func (id *ID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidID, v)
+		}
+		*id = ID(v)
+		return nil
+	case []byte:
+		return id.UnmarshalText(v)
+	case string:
+		return id.UnmarshalText([]byte(v))
+	case nil:
+		return fmt.Errorf("%w: NULL", ErrInvalidID)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidID, src)
+	}
+}
+
+// Value Stores the ID as an integer column
+// @return driver.Value - the ID as an int64
+// @return error - always nil
+func (id ID) Value() (driver.Value, error) {
+	return int64(id), nil
+}
+
+// GenerateID Generates a unique ID like Generate, returned as an ID
+// @return ID - the generated unique ID
+// @return error - any error that occurred during generation
+func (rs *RedisSnowflake) GenerateID() (ID, error) {
+	id, err := rs.Generate()
+	return ID(id), err
+}
+
+// GenerateIDs Generates n unique IDs like GenerateN, returned as IDs
+// @param n - int number of IDs to generate
+// @return []ID - the generated IDs in generation order
+// @return error - any error that occurred during generation
+func (rs *RedisSnowflake) GenerateIDs(n int) ([]ID, error) {
+	raw, err := rs.GenerateN(n)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	ids := make([]ID, len(raw))
+	for i, id := range raw {
+		ids[i] = ID(id)
+	}
+	return ids, nil
+}
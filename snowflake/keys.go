@@ -0,0 +1,122 @@
+package snowflake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultKeyPrefix is the prefix of every Redis key used by the package
+const DefaultKeyPrefix = "snowflake"
+
+// globEscaper escapes the characters that are special in SCAN patterns
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// keyspace Builds the Redis keys of one ID domain as <prefix>:<name>
+// The zero value uses DefaultKeyPrefix
+type keyspace struct {
+	prefix string
+}
+
+// base returns the configured prefix or DefaultKeyPrefix
+func (k keyspace) base() string {
+	if k.prefix == "" {
+		return DefaultKeyPrefix
+	}
+	return k.prefix
+}
+
+// datacenterCounter returns the counter allocating datacenter IDs
+func (k keyspace) datacenterCounter() string {
+	return k.base() + ":next_datacenter_id"
+}
+
+// workerCounter returns the counter allocating worker IDs
+func (k keyspace) workerCounter() string {
+	return k.base() + ":next_worker_id"
+}
+
+// slotCounter returns the counter spreading leased nodes over the slots
+func (k keyspace) slotCounter() string {
+	return k.base() + ":next_slot"
+}
+
+// counters returns every allocation counter
+func (k keyspace) counters() []string {
+	return []string{k.datacenterCounter(), k.workerCounter(), k.slotCounter()}
+}
+
+// id returns the strict-mode key recording an issued ID
+func (k keyspace) id(id int64) string {
+	return fmt.Sprintf("%s:id:%d", k.base(), id)
+}
+
+// slot returns the key leasing a worker slot
+func (k keyspace) slot(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s:slot:%d:%d", k.base(), datacenterID, workerID)
+}
+
+// watermark returns the key holding the high-water mark of a worker slot
+func (k keyspace) watermark(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s:hwm:%d:%d", k.base(), datacenterID, workerID)
+}
+
+// firstUse returns the key recording when a worker slot was first leased by a live node
+func (k keyspace) firstUse(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s:first_use:%d:%d", k.base(), datacenterID, workerID)
+}
+
+// backfill returns the key claiming a worker slot reserved for backfill for one generator
+func (k keyspace) backfill(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s:backfill:%d:%d", k.base(), datacenterID, workerID)
+}
+
+// backfillSequence returns the counter of the sequence numbers backfill used in one millisecond of a slot
+func (k keyspace) backfillSequence(datacenterID, workerID, timestamp int64) string {
+	return fmt.Sprintf("%s:backfill_seq:%d:%d:%d", k.base(), datacenterID, workerID, timestamp)
+}
+
+// backfillSequences returns the SCAN pattern matching every backfill sequence counter of a slot
+func (k keyspace) backfillSequences(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s:backfill_seq:%d:%d:*", globEscaper.Replace(k.base()), datacenterID, workerID)
+}
+
+// pattern returns the SCAN pattern matching every key of a kind such as "slot" or "id"
+func (k keyspace) pattern(kind string) string {
+	return globEscaper.Replace(k.base()) + ":" + kind + ":*"
+}
+
+// parseSlot extracts the node of a slot key
+// @param key - string key returned by a scan of pattern("slot")
+// @return int64 - the datacenter ID
+// @return int64 - the worker ID
+// @return bool - false if the key is not a slot key
+func (k keyspace) parseSlot(key string) (int64, int64, bool) {
+	rest := strings.TrimPrefix(key, k.base()+":slot:")
+	datacenter, worker, found := strings.Cut(rest, ":")
+	if rest == key || !found {
+		return 0, 0, false
+	}
+	datacenterID, err := strconv.ParseInt(datacenter, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	workerID, err := strconv.ParseInt(worker, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return datacenterID, workerID, true
+}
+
+// parseID extracts the ID of a strict-mode key
+// @param key - string key returned by a scan of pattern("id")
+// @return int64 - the ID
+// @return bool - false if the key is not a strict-mode key
+func (k keyspace) parseID(key string) (int64, bool) {
+	rest := strings.TrimPrefix(key, k.base()+":id:")
+	if rest == key {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(rest, 10, 64)
+	return id, err == nil
+}
@@ -0,0 +1,139 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+)
+
+// idBits is the number of usable ID bits, the sign bit is always 0
+const idBits = 63
+
+// ErrInvalidLayout represents a layout whose parts do not fit in an ID
+var ErrInvalidLayout = errors.New("invalid layout")
+
+// Layout Describes how the 63 usable bits of an ID are divided between its parts
+type Layout struct {
+	Epoch          int64 // Timestamp offset in milliseconds since the Unix epoch
+	DatacenterBits uint  // Number of datacenter ID bits
+	WorkerBits     uint  // Number of worker ID bits
+	SequenceBits   uint  // Number of sequence bits
+}
+
+// DefaultLayout is the classic snowflake layout: 41 timestamp, 5 datacenter, 5 worker and 12 sequence bits
+var DefaultLayout = Layout{
+	Epoch:          Epoch,
+	DatacenterBits: datacenterBits,
+	WorkerBits:     workerBits,
+	SequenceBits:   sequenceBits,
+}
+
+// MaxDatacenterID Returns the largest datacenter ID that fits in the layout
+// @return int64 - the maximum datacenter ID
+func (l Layout) MaxDatacenterID() int64 {
+	return -1 ^ (-1 << l.DatacenterBits)
+}
+
+// MaxWorkerID Returns the largest worker ID that fits in the layout
+// @return int64 - the maximum worker ID
+func (l Layout) MaxWorkerID() int64 {
+	return -1 ^ (-1 << l.WorkerBits)
+}
+
+// MaxSequence Returns the largest sequence number that fits in the layout
+// @return int64 - the maximum sequence number
+func (l Layout) MaxSequence() int64 {
+	return -1 ^ (-1 << l.SequenceBits)
+}
+
+// TimestampBits Returns the number of bits left for the timestamp
+// @return uint - the timestamp bits (0 if the layout is invalid)
+func (l Layout) TimestampBits() uint {
+	used := l.DatacenterBits + l.WorkerBits + l.SequenceBits
+	if used >= idBits {
+		return 0
+	}
+	return idBits - used
+}
+
+// MaxTimestamp Returns the largest timestamp offset from Epoch that fits in the layout
+// @return int64 - the maximum timestamp offset in milliseconds
+func (l Layout) MaxTimestamp() int64 {
+	return -1 ^ (-1 << l.TimestampBits())
+}
+
+// Validate Checks that every part fits in the 63 usable bits of an ID
+// @return error - ErrInvalidLayout describing the problem
+func (l Layout) Validate() error {
+	if l.Epoch < 0 {
+		return fmt.Errorf("%w: negative epoch %d", ErrInvalidLayout, l.Epoch)
+	}
+	if l.SequenceBits == 0 {
+		return fmt.Errorf("%w: at least one sequence bit is required", ErrInvalidLayout)
+	}
+	if l.TimestampBits() == 0 {
+		return fmt.Errorf("%w: %d datacenter, %d worker and %d sequence bits leave no timestamp bits",
+			ErrInvalidLayout, l.DatacenterBits, l.WorkerBits, l.SequenceBits)
+	}
+	return nil
+}
+
+// workerShift returns the bit offset of the worker ID
+func (l Layout) workerShift() uint {
+	return l.SequenceBits
+}
+
+// datacenterShift returns the bit offset of the datacenter ID
+func (l Layout) datacenterShift() uint {
+	return l.SequenceBits + l.WorkerBits
+}
+
+// timestampShift returns the bit offset of the timestamp
+func (l Layout) timestampShift() uint {
+	return l.SequenceBits + l.WorkerBits + l.DatacenterBits
+}
+
+// compose combines the parts of an ID
+// @param timestamp - int64 milliseconds since the Unix epoch
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @param sequence - int64 sequence number
+// @return int64 - the composed ID
+// @return error - ErrTimestampOverflow if the timestamp is outside the layout's range
+func (l Layout) compose(timestamp, datacenterID, workerID, sequence int64) (int64, error) {
+	offset := timestamp - l.Epoch
+	if offset < 0 || offset > l.MaxTimestamp() {
+		return 0, ErrTimestampOverflow
+	}
+	return offset<<l.timestampShift() |
+		datacenterID<<l.datacenterShift() |
+		workerID<<l.workerShift() |
+		sequence, nil
+}
+
+// validateNode checks that the datacenter ID and worker ID fit in the layout
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return error - ErrInvalidDatacenter or ErrInvalidWorker if either is out of range
+func (l Layout) validateNode(datacenterID, workerID int64) error {
+	if datacenterID < 0 || datacenterID > l.MaxDatacenterID() {
+		return ErrInvalidDatacenter
+	}
+	if workerID < 0 || workerID > l.MaxWorkerID() {
+		return ErrInvalidWorker
+	}
+	return nil
+}
+
+// maxNode Returns the largest combined node number (datacenter and worker bits together)
+// @return int64 - the maximum combined node number
+func (l Layout) maxNode() int64 {
+	return -1 ^ (-1 << (l.DatacenterBits + l.WorkerBits))
+}
+
+// splitNode splits a combined node number into datacenter ID and worker ID
+// @param n - int64 combined node number, the worker ID occupies the lowest WorkerBits bits
+// @return int64 - the datacenter ID (may exceed the layout if n does)
+// @return int64 - the worker ID
+func (l Layout) splitNode(n int64) (int64, int64) {
+	return n >> l.WorkerBits, n & l.MaxWorkerID()
+}
@@ -0,0 +1,387 @@
+package snowflake
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+)
+
+var (
+	// ErrSlotTaken represents a worker slot leased by another node
+	ErrSlotTaken = errors.New("worker slot is held by another node")
+	// ErrNoFreeSlot represents a layout whose worker slots are all leased
+	ErrNoFreeSlot = errors.New("no free worker slot")
+	// ErrLeaseLost represents a fenced instance whose slot lease could not be confirmed before expiry
+	ErrLeaseLost = errors.New("worker slot lease lost")
+	// ErrLeaseVanished represents a slot key that disappeared from Redis, e.g. after expiry or a flush
+	ErrLeaseVanished = errors.New("worker slot lease key vanished")
+	// ErrFencingWithoutLease represents fencing enabled on an instance that holds no slot lease
+	ErrFencingWithoutLease = errors.New("fencing requires a Redis client and a lease TTL")
+)
+
+// LeaseInfo Value stored in the Redis key of a leased worker slot
+type LeaseInfo struct {
+	Holder     string `json:"holder"`      // Identifies the process holding the slot
+	AcquiredAt int64  `json:"acquired_at"` // Milliseconds since the Unix epoch when the slot was leased
+}
+
+// leaseClient Redis operations needed to hold a lease
+type leaseClient interface {
+	redis.Client
+	redis.KeyValueClient
+	redis.CompareAndExpireClient
+	redis.CompareAndDeleteClient
+}
+
+// lease A worker slot held in Redis with a TTL
+type lease struct {
+	client    leaseClient
+	key       string
+	firstUse  string // Key recording the first live use of the slot, see Admin.Backfill
+	holder    string // Holder written to the LeaseInfo of every slot this process leases
+	value     string // Encoded LeaseInfo, compared before renewing or releasing
+	ttl       time.Duration
+	expiresAt atomic.Int64 // Local milliseconds at which the lease expires unless renewed
+	metrics   *metrics     // Records Redis calls once the lease belongs to an instance
+}
+
+// defaultLeaseHolder returns hostname/pid/random so concurrent processes never share a holder
+// @return string - the holder name
+func defaultLeaseHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// newLease prepares a lease on a worker slot without acquiring it
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return *lease - the prepared lease
+// @return error - ErrUnsupportedClient if the client cannot hold leases
+func (builder *RedisSnowflakeBuilder) newLease(datacenterID, workerID int64) (*lease, error) {
+	client, ok := builder.client.(leaseClient)
+	if !ok {
+		return nil, fmt.Errorf("%w: leases require Get, Set, CompareAndExpire and CompareAndDelete", ErrUnsupportedClient)
+	}
+	holder := builder.leaseHolder
+	if holder == "" {
+		holder = defaultLeaseHolder()
+	}
+	value, err := encodeLeaseInfo(holder, millis(builder.clock.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return &lease{
+		client:   client,
+		key:      builder.keys.slot(datacenterID, workerID),
+		firstUse: builder.keys.firstUse(datacenterID, workerID),
+		holder:   holder,
+		value:    value,
+		ttl:      builder.leaseTTL,
+	}, nil
+}
+
+// encodeLeaseInfo encodes the value of a slot key
+// @param holder - string identifying the process holding the slot
+// @param now - int64 milliseconds since the Unix epoch at which the slot is leased
+// @return string - the JSON encoded LeaseInfo
+// @return error - any error that occurred while encoding
+func encodeLeaseInfo(holder string, now int64) (string, error) {
+	value, err := json.Marshal(LeaseInfo{Holder: holder, AcquiredAt: now})
+	return string(value), err
+}
+
+// acquire tries to lease the slot
+// The first live use of the slot is recorded once it is held, so backfill never issues IDs the node
+// may issue; if that fails the slot is released again
+// @param ctx - context for the operation
+// @param now - int64 current local milliseconds
+// @return bool - true if the slot was free and is now held
+// @return error - any error that occurred while talking to Redis
+func (l *lease) acquire(ctx context.Context, now int64) (bool, error) {
+	start := time.Now()
+	ok, err := l.client.SetNX(ctx, l.key, l.value, l.ttl)
+	l.metrics.observeRedis(start, err)
+	if err != nil || !ok {
+		return false, err
+	}
+	start = time.Now()
+	_, err = l.client.SetNX(ctx, l.firstUse, now, 0)
+	l.metrics.observeRedis(start, err)
+	if err != nil {
+		_ = l.release(ctx)
+		return false, err
+	}
+	l.expiresAt.Store(now + l.ttl.Milliseconds())
+	return true, nil
+}
+
+// renew extends the lease if it is still held by this process
+// The holder is compared and the TTL extended in one atomic step; only a failed renewal reads the key
+// to tell a vanished key from a taken one. A vanished key is re-acquired if reclaim is set; otherwise
+// another node may have used the slot meanwhile, so it is reported instead
+// @param ctx - context for the operation
+// @param now - int64 current local milliseconds
+// @param reclaim - bool re-acquiring the slot if its key vanished
+// @return error - ErrSlotTaken if another node holds the slot, ErrLeaseVanished if the key is gone
+// and reclaim is not set, or any Redis error
+func (l *lease) renew(ctx context.Context, now int64, reclaim bool) error {
+	start := time.Now()
+	ok, err := l.client.CompareAndExpire(ctx, l.key, l.value, l.ttl)
+	l.metrics.observeRedis(start, err)
+	if err != nil {
+		return err
+	}
+	if ok {
+		l.expiresAt.Store(now + l.ttl.Milliseconds())
+		return nil
+	}
+
+	start = time.Now()
+	_, err = l.client.Get(ctx, l.key)
+	l.metrics.observeRedis(start, ignoreNil(err))
+	if !errors.Is(err, redis.ErrNil) {
+		if err != nil {
+			return err
+		}
+		return ErrSlotTaken
+	}
+	if !reclaim {
+		return ErrLeaseVanished
+	}
+	ok, err = l.acquire(ctx, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSlotTaken
+	}
+	return nil
+}
+
+// release deletes the slot key if it is still held by this process, comparing and deleting atomically
+// @param ctx - context for the operation
+// @return error - any error that occurred while talking to Redis
+func (l *lease) release(ctx context.Context) error {
+	start := time.Now()
+	_, err := l.client.CompareAndDelete(ctx, l.key, l.value)
+	l.metrics.observeRedis(start, err)
+	return err
+}
+
+// ignoreNil treats a missing key as a successful Redis call
+func ignoreNil(err error) error {
+	if errors.Is(err, redis.ErrNil) {
+		return nil
+	}
+	return err
+}
+
+// createLeasedInstance allocates a free worker slot from Redis and leases it
+// Candidates come from the <prefix>:next_slot counter, so nodes spread over every slot of the layout
+// @param client - redis.Client interface implementation
+// @return *RedisSnowflake - the created instance holding the slot lease
+// @return error - ErrNoFreeSlot if every slot is leased, or any Redis error
+func (builder *RedisSnowflakeBuilder) createLeasedInstance(client redis.Client) (*RedisSnowflake, error) {
+	ctx := context.Background()
+	slots := builder.layout.maxNode() + 1
+	for attempt := int64(0); attempt < slots; attempt++ {
+		n, err := client.Incr(ctx, builder.keys.slotCounter())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get worker slot from Redis: %w", err)
+		}
+		datacenterID, workerID := builder.layout.splitNode((n - 1) % slots)
+
+		l, err := builder.newLease(datacenterID, workerID)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := l.acquire(ctx, millis(builder.clock.Now()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to lease worker slot: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		rs, err := builder.createInstance(datacenterID, workerID, client)
+		if err != nil {
+			_ = l.release(ctx)
+			return nil, err
+		}
+		l.metrics = rs.metrics
+		rs.lease.Store(l)
+		return rs, nil
+	}
+	return nil, ErrNoFreeSlot
+}
+
+// leaseSlot leases the fixed slot of an instance built from manual or provided IDs
+// @param rs - *RedisSnowflake whose slot to lease
+// @return error - ErrSlotTaken if another node holds the slot, or any Redis error
+func (builder *RedisSnowflakeBuilder) leaseSlot(rs *RedisSnowflake) error {
+	l, err := builder.newLease(rs.node.datacenterID, rs.node.workerID)
+	if err != nil {
+		return err
+	}
+	l.metrics = rs.metrics
+	ok, err := l.acquire(rs.ctx, rs.currentTimeMillis())
+	if err != nil {
+		return fmt.Errorf("failed to lease worker slot: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %d/%d", ErrSlotTaken, rs.node.datacenterID, rs.node.workerID)
+	}
+	rs.lease.Store(l)
+	return nil
+}
+
+// runLease renews the slot lease every third of its TTL until the instance is cleaned up
+// With fencing, a lost lease stops generation until a slot is leased again
+// @param logger - Logger receiving renewal failures
+func (rs *RedisSnowflake) runLease(logger Logger) {
+	defer rs.wg.Done()
+	interval := rs.lease.Load().ttl / 3
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lost := false
+	for {
+		select {
+		case <-rs.stop:
+			return
+		case <-ticker.C:
+			now := rs.currentTimeMillis()
+			l := rs.lease.Load()
+			// Under fencing a vanished key counts as lost, as the slot may have been used meanwhile
+			err := l.renew(rs.ctx, now, !rs.fencing)
+			if err == nil {
+				lost = false
+				rs.fenced.Store(false)
+				continue
+			}
+			logger.Printf("snowflake: failed to renew lease on %s: %v", l.key, err)
+			rs.emit(func(o Observer) { o.OnLeaseRenewFailed(err) })
+			if !lost && (errors.Is(err, ErrSlotTaken) || errors.Is(err, ErrLeaseVanished) || l.expiresAt.Load() <= now) {
+				lost = true
+				rs.emit(func(o Observer) { o.OnLeaseLost() })
+			}
+			if lost && rs.fencing {
+				rs.fenced.Store(true)
+				if err := rs.reacquireLease(now); err != nil {
+					logger.Printf("snowflake: failed to lease a worker slot: %v", err)
+					continue
+				}
+				lost = false
+				rs.fenced.Store(false)
+			}
+		}
+	}
+}
+
+// fence refuses generation while fencing is enabled and the lease is not confirmed
+// @return error - ErrLeaseLost if the lease was lost or has run out without renewal
+func (rs *RedisSnowflake) fence() error {
+	if !rs.fencing {
+		return nil
+	}
+	if rs.fenced.Load() || rs.lease.Load().expiresAt.Load() <= rs.currentTimeMillis() {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// reacquireLease leases a free slot from the <prefix>:next_slot counter after the lease was lost
+// With a watermark store it waits like Build for the clock to pass the high-water mark of the new
+// slot, so IDs of its previous holder are not issued again; the instance stays fenced meanwhile
+// The node switches to the new slot; the last timestamp is kept, so IDs continue to increase
+// The old slot key is released afterwards in case it still names this process
+// @param now - int64 current local milliseconds
+// @return error - ErrNoFreeSlot if every slot is leased, ErrClockBehindWatermark if the clock does
+// not pass the mark of the new slot in time, or any Redis error
+func (rs *RedisSnowflake) reacquireLease(now int64) error {
+	old := rs.lease.Load()
+	slots := rs.layout.maxNode() + 1
+	for attempt := int64(0); attempt < slots; attempt++ {
+		start := time.Now()
+		n, err := old.client.Incr(rs.ctx, rs.keys.slotCounter())
+		rs.metrics.observeRedis(start, err)
+		if err != nil {
+			return err
+		}
+		datacenterID, workerID := rs.layout.splitNode((n - 1) % slots)
+
+		value, err := encodeLeaseInfo(old.holder, now)
+		if err != nil {
+			return err
+		}
+		l := &lease{
+			client:   old.client,
+			key:      rs.keys.slot(datacenterID, workerID),
+			firstUse: rs.keys.firstUse(datacenterID, workerID),
+			holder:   old.holder,
+			value:    value,
+			ttl:      old.ttl,
+			metrics:  rs.metrics,
+		}
+		ok, err := l.acquire(rs.ctx, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		mark, err := rs.slotWatermark(datacenterID, workerID)
+		if err != nil {
+			_ = l.release(rs.ctx)
+			return err
+		}
+
+		rs.node.Lock()
+		rs.node.datacenterID, rs.node.workerID = datacenterID, workerID
+		if mark > rs.node.lastTimestamp {
+			rs.node.lastTimestamp = mark
+		}
+		rs.node.Unlock()
+		rs.lease.Store(l)
+		_ = old.release(rs.ctx)
+		rs.emit(func(o Observer) { o.OnAllocated(datacenterID, workerID) })
+		return nil
+	}
+	return ErrNoFreeSlot
+}
+
+// slotWatermark loads the high-water mark of a slot and waits for the clock to pass it
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return int64 - the stored mark, 0 without a watermark store
+// @return error - ErrClockBehindWatermark if the clock does not pass the mark in time, or a load error
+func (rs *RedisSnowflake) slotWatermark(datacenterID, workerID int64) (int64, error) {
+	if rs.watermarkStore == nil {
+		return 0, nil
+	}
+	mark, err := rs.watermarkStore.Load(rs.ctx, datacenterID, workerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load high-water mark: %w", err)
+	}
+	if err := rs.waitPastWatermark(mark, rs.watermarkMaxWait); err != nil {
+		return 0, err
+	}
+	return mark, nil
+}
@@ -0,0 +1,9 @@
+package snowflake
+
+// Logger Interface for warnings emitted by background checks, satisfied by *log.Logger
+type Logger interface {
+	// Printf logs a formatted message
+	// @param format - string format as in fmt.Printf
+	// @param args - ...interface{} format arguments
+	Printf(format string, args ...interface{})
+}
@@ -0,0 +1,92 @@
+package snowflake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RedisLatencyBuckets are the upper bounds of the Redis latency histogram buckets
+var RedisLatencyBuckets = []time.Duration{
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// LatencyHistogram Snapshot of a latency distribution
+type LatencyHistogram struct {
+	Bounds []time.Duration // Upper bound of each bucket, RedisLatencyBuckets
+	Counts []uint64        // Observations per bucket, not cumulative; the last entry counts those above every bound
+	Count  uint64          // Total number of observations
+	Sum    time.Duration   // Sum of all observations
+}
+
+// histogram Lock-free latency histogram over RedisLatencyBuckets
+type histogram struct {
+	counts []atomic.Uint64
+	sum    atomic.Int64
+}
+
+// newHistogram creates an empty histogram
+func newHistogram() *histogram {
+	return &histogram{counts: make([]atomic.Uint64, len(RedisLatencyBuckets)+1)}
+}
+
+// observe records one duration
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(RedisLatencyBuckets) && d > RedisLatencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// snapshot returns the current distribution
+func (h *histogram) snapshot() LatencyHistogram {
+	snap := LatencyHistogram{
+		Bounds: RedisLatencyBuckets,
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    time.Duration(h.sum.Load()),
+	}
+	for i := range h.counts {
+		snap.Counts[i] = h.counts[i].Load()
+		snap.Count += snap.Counts[i]
+	}
+	return snap
+}
+
+// metrics Counters updated by generation and background work
+type metrics struct {
+	generated      atomic.Uint64
+	exhaustionWait atomic.Int64 // Nanoseconds spent waiting for the next millisecond
+	rollbacks      atomic.Uint64
+	strictRetries  atomic.Uint64
+	redisErrors    atomic.Uint64
+	redisLatency   *histogram
+}
+
+// newMetrics creates zeroed metrics
+func newMetrics() *metrics {
+	return &metrics{redisLatency: newHistogram()}
+}
+
+// observeRedis records the latency and outcome of a Redis call, ignoring a nil receiver
+// @param start - time.Time the call started
+// @param err - error returned by the call
+func (m *metrics) observeRedis(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.redisLatency.observe(time.Since(start))
+	if err != nil {
+		m.redisErrors.Add(1)
+	}
+}
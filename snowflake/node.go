@@ -4,7 +4,6 @@ package snowflake
 import (
 	"errors"
 	"sync"
-	"time"
 )
 
 const (
@@ -17,18 +16,6 @@ const (
 	workerBits = 5
 	// Number of sequence bits
 	sequenceBits = 12
-
-	// Maximum datacenter ID and worker ID
-	maxDatacenterID = -1 ^ (-1 << datacenterBits) // 31
-	maxWorkerID     = -1 ^ (-1 << workerBits)     // 31
-
-	// Maximum sequence number
-	maxSequence = -1 ^ (-1 << sequenceBits) // 4095
-
-	// Bit shifts for each part
-	workerShift     = sequenceBits
-	datacenterShift = sequenceBits + workerBits
-	timestampShift  = sequenceBits + workerBits + datacenterBits
 )
 
 var (
@@ -40,6 +27,12 @@ var (
 	ErrInvalidWorker = errors.New("invalid worker ID")
 	// ErrOverFlow represents a sequence overflow error
 	ErrOverFlow = errors.New("sequence number exceeds maximum value")
+	// ErrClockRollback represents the clock moving backwards
+	ErrClockRollback = errors.New("clock rollback error")
+	// ErrTimestampOverflow represents a timestamp outside the range representable since Epoch
+	ErrTimestampOverflow = errors.New("timestamp exceeds the representable range")
+	// ErrInvalidInterval represents a non-positive interval of a periodic background task
+	ErrInvalidInterval = errors.New("interval must be positive")
 )
 
 // Node Snowflake algorithm node structure
@@ -48,6 +41,7 @@ type Node struct {
 	datacenterID  int64 // Datacenter ID for snowflake ID generation
 	workerID      int64 // Worker ID for snowflake ID generation
 	sequence      int64 // Sequence number for snowflake ID generation
+	sequenceStart int64 // Sequence number the current millisecond started at
 	lastTimestamp int64 // Timestamp of last generated ID
 }
 
@@ -57,12 +51,18 @@ type Node struct {
 // @return *Node - the created Node instance
 // @return error - any error that occurred during creation
 func NewNode(datacenterID int64, workerID int64) (*Node, error) {
-	if datacenterID < 0 || datacenterID > maxDatacenterID {
-		return nil, ErrInvalidDatacenter
-	}
+	return newNode(DefaultLayout, datacenterID, workerID)
+}
 
-	if workerID < 0 || workerID > maxWorkerID {
-		return nil, ErrInvalidWorker
+// newNode Creates a new snowflake algorithm node for the given layout
+// @param layout - Layout the IDs must fit in
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return *Node - the created Node instance
+// @return error - any error that occurred during creation
+func newNode(layout Layout, datacenterID int64, workerID int64) (*Node, error) {
+	if err := layout.validateNode(datacenterID, workerID); err != nil {
+		return nil, err
 	}
 
 	return &Node{
@@ -73,8 +73,11 @@ func NewNode(datacenterID int64, workerID int64) (*Node, error) {
 	}, nil
 }
 
-// currentTimeMillis Gets current timestamp in milliseconds
-// @return int64 - current timestamp in milliseconds
-func currentTimeMillis() int64 {
-	return time.Now().UnixNano() / int64(time.Millisecond)
+// ids returns the datacenter and worker ID, which may change when a fenced instance moves to another slot
+// @return int64 - the datacenter ID
+// @return int64 - the worker ID
+func (n *Node) ids() (int64, int64) {
+	n.Lock()
+	defer n.Unlock()
+	return n.datacenterID, n.workerID
 }
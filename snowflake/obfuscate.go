@@ -0,0 +1,203 @@
+package snowflake
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+const (
+	// MinObfuscationKeyLength is the minimum length of an obfuscation secret in bytes
+	MinObfuscationKeyLength = 16
+	// obfuscationRounds is the number of Feistel rounds
+	obfuscationRounds = 8
+	// obfuscationClockTolerance is how far in the future a deobfuscated ID may lie
+	obfuscationClockTolerance = time.Minute
+)
+
+// ErrInvalidObfuscationKey represents an obfuscation key ring that cannot be used
+var ErrInvalidObfuscationKey = errors.New("invalid obfuscation key")
+
+// ObfuscationKey Secret of one generation of the obfuscation key ring
+type ObfuscationKey struct {
+	Secret []byte    // At least MinObfuscationKeyLength random bytes
+	Since  time.Time // IDs generated from this time on use the key; ignored for the oldest key
+}
+
+// obfuscationWindow Layer of the obfuscation, permuting the IDs from low on with one key
+type obfuscationWindow struct {
+	low   uint64 // First ID of the range, which extends to the largest ID
+	size  uint64 // Number of IDs in the range
+	half  uint   // Bits per Feistel half, enough to cover size
+	block cipher.Block
+}
+
+// Obfuscator Keyed, reversible permutation of IDs that hides their order and volume
+// The oldest key permutes the whole 63-bit space; every later key first permutes the IDs from its Since on among
+// themselves, so the result is still a permutation, IDs before the rotation keep their obfuscated form and IDs
+// after it depend on the new key
+type Obfuscator struct {
+	layout  Layout
+	windows []obfuscationWindow // Ordered by low
+	clock   Clock
+}
+
+// NewObfuscator Creates an obfuscator for IDs of the layout
+// When rotating, give the new key a Since no earlier than the time it is deployed
+// @param layout - Layout the IDs were generated with
+// @param keys - ...ObfuscationKey key ring, in any order
+// @return *Obfuscator - the created obfuscator
+// @return error - ErrInvalidLayout, or ErrInvalidObfuscationKey for a missing or short secret or duplicate Since
+func NewObfuscator(layout Layout, keys ...ObfuscationKey) (*Obfuscator, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: at least one key is required", ErrInvalidObfuscationKey)
+	}
+	keys = append([]ObfuscationKey(nil), keys...)
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Since.Before(keys[j].Since) })
+
+	o := &Obfuscator{layout: layout, clock: systemClock{}}
+	for i, key := range keys {
+		if len(key.Secret) < MinObfuscationKeyLength {
+			return nil, fmt.Errorf("%w: secret of the key since %v has %d bytes, at least %d are required",
+				ErrInvalidObfuscationKey, key.Since, len(key.Secret), MinObfuscationKeyLength)
+		}
+		var low uint64
+		if i > 0 {
+			low = o.firstIDAt(key.Since)
+			if low <= o.firstIDAt(keys[i-1].Since) {
+				return nil, fmt.Errorf("%w: two keys start at the same ID, since %v", ErrInvalidObfuscationKey, key.Since)
+			}
+		}
+		digest := sha256.Sum256(key.Secret)
+		block, err := aes.NewCipher(digest[:16])
+		if err != nil {
+			return nil, err
+		}
+		o.windows = append(o.windows, obfuscationWindow{low: low, block: block})
+	}
+	for i := range o.windows {
+		w := &o.windows[i]
+		w.size = uint64(math.MaxInt64) + 1 - w.low
+		w.half = uint(bits.Len64(w.size-1)+1) / 2
+		if w.half == 0 {
+			w.half = 1
+		}
+	}
+	return o, nil
+}
+
+// SetClock Sets the clock Deobfuscate compares timestamps with
+// @param clock - Clock providing the current time
+// @return *Obfuscator - the obfuscator for method chaining
+func (o *Obfuscator) SetClock(clock Clock) *Obfuscator {
+	o.clock = clock
+	return o
+}
+
+// firstIDAt returns the smallest ID of the layout at a time, clamped to the representable range
+// @param t - time.Time to convert
+// @return uint64 - the ID
+func (o *Obfuscator) firstIDAt(t time.Time) uint64 {
+	offset := millis(t) - o.layout.Epoch
+	if offset < 0 {
+		return 0
+	}
+	if offset > o.layout.MaxTimestamp() {
+		offset = o.layout.MaxTimestamp()
+	}
+	return uint64(offset) << o.layout.timestampShift()
+}
+
+// Obfuscate Maps an ID to a random-looking ID
+// @param id - ID to obfuscate
+// @return ID - the obfuscated ID
+// @return error - ErrInvalidID if the ID is negative
+func (o *Obfuscator) Obfuscate(id ID) (ID, error) {
+	if id < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidID, id)
+	}
+	x := uint64(id)
+	// Newest layer first; each keeps x inside its range, so every older layer applies as well
+	for i := len(o.windows) - 1; i >= 0; i-- {
+		if w := &o.windows[i]; x >= w.low {
+			x = w.low + w.walk(x-w.low, w.encrypt)
+		}
+	}
+	return ID(x), nil
+}
+
+// Deobfuscate Recovers the ID an obfuscated ID was created from
+// @param id - ID returned by Obfuscate
+// @return ID - the original ID
+// @return error - ErrInvalidID if the ID is negative or does not decode to a plausible, non-future ID
+func (o *Obfuscator) Deobfuscate(id ID) (ID, error) {
+	if id < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidID, id)
+	}
+	x := uint64(id)
+	// Undoing a layer tells whether the next one was applied: only IDs from its low on went through it
+	for i := range o.windows {
+		if w := &o.windows[i]; x >= w.low {
+			x = w.low + w.walk(x-w.low, w.decrypt)
+		}
+	}
+	original := ID(x)
+
+	// Random values mostly decode into the future, which exposes guessed or tampered IDs
+	limit := millis(o.clock.Now().Add(obfuscationClockTolerance))
+	if c, _ := o.layout.Decode(int64(original)); c.Timestamp > limit {
+		return 0, fmt.Errorf("%w: %d decodes to a future time", ErrInvalidID, id)
+	}
+	return original, nil
+}
+
+// walk applies the Feistel permutation until the result falls inside the window again (cycle walking)
+// @param x - uint64 offset within the window
+// @param permute - func(uint64) uint64 encrypt or decrypt
+// @return uint64 - the permuted offset
+func (w *obfuscationWindow) walk(x uint64, permute func(uint64) uint64) uint64 {
+	x = permute(x)
+	for x >= w.size {
+		x = permute(x)
+	}
+	return x
+}
+
+// encrypt runs the Feistel rounds forwards over 2*half bits
+func (w *obfuscationWindow) encrypt(x uint64) uint64 {
+	mask := uint64(1)<<w.half - 1
+	left, right := x>>w.half, x&mask
+	for round := 0; round < obfuscationRounds; round++ {
+		left, right = right, left^w.round(round, right)&mask
+	}
+	return left<<w.half | right
+}
+
+// decrypt runs the Feistel rounds backwards over 2*half bits
+func (w *obfuscationWindow) decrypt(x uint64) uint64 {
+	mask := uint64(1)<<w.half - 1
+	left, right := x>>w.half, x&mask
+	for round := obfuscationRounds - 1; round >= 0; round-- {
+		left, right = right^w.round(round, left)&mask, left
+	}
+	return left<<w.half | right
+}
+
+// round is the keyed round function, AES of the round number and half block
+func (w *obfuscationWindow) round(round int, half uint64) uint64 {
+	var block [aes.BlockSize]byte
+	block[0] = byte(round)
+	binary.BigEndian.PutUint64(block[8:], half)
+	w.block.Encrypt(block[:], block[:])
+	return binary.BigEndian.Uint64(block[:8])
+}
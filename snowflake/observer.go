@@ -0,0 +1,99 @@
+package snowflake
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStrictWithoutRedis represents strict mode enabled without a Redis client, so IDs are generated locally
+var ErrStrictWithoutRedis = errors.New("strict mode has no Redis client")
+
+// observerQueueSize is how many events may wait for the observer before new ones are dropped
+const observerQueueSize = 256
+
+// Observer Receives notable events of a RedisSnowflake instance
+// Callbacks run on a dedicated goroutine in the order the events happened, never on the generation path;
+// events are dropped while the observer is more than observerQueueSize events behind
+type Observer interface {
+	// OnAllocated is called once Build has assigned the node, and again when fencing moves it to another slot
+	// @param datacenterID - int64 datacenter ID of the instance
+	// @param workerID - int64 worker ID of the instance
+	OnAllocated(datacenterID, workerID int64)
+	// OnClockRollback is called when generation is refused because the clock moved backwards
+	// @param drift - time.Duration the clock is behind the last issued timestamp
+	OnClockRollback(drift time.Duration)
+	// OnSequenceExhausted is called when the sequence of a millisecond is used up
+	// @param wait - time.Duration spent waiting for the next millisecond, 0 under ExhaustionError
+	OnSequenceExhausted(wait time.Duration)
+	// OnLeaseRenewFailed is called for every failed renewal of the slot lease
+	// @param err - error returned by the renewal
+	OnLeaseRenewFailed(err error)
+	// OnLeaseLost is called once when the slot lease expires or is taken by another node
+	OnLeaseLost()
+	// OnStrictFallback is called once by Build when strict mode falls back to local generation
+	// because no Redis client is configured
+	// @param err - ErrStrictWithoutRedis
+	OnStrictFallback(err error)
+}
+
+// NopObserver Observer ignoring every event, embed it to implement only some callbacks
+type NopObserver struct{}
+
+// OnAllocated Ignores the event
+func (NopObserver) OnAllocated(int64, int64) {}
+
+// OnClockRollback Ignores the event
+func (NopObserver) OnClockRollback(time.Duration) {}
+
+// OnSequenceExhausted Ignores the event
+func (NopObserver) OnSequenceExhausted(time.Duration) {}
+
+// OnLeaseRenewFailed Ignores the event
+func (NopObserver) OnLeaseRenewFailed(error) {}
+
+// OnLeaseLost Ignores the event
+func (NopObserver) OnLeaseLost() {}
+
+// OnStrictFallback Ignores the event
+func (NopObserver) OnStrictFallback(error) {}
+
+// startObserver starts delivering events to the observer until the instance is closed
+// @param observer - Observer receiving the events
+func (rs *RedisSnowflake) startObserver(observer Observer) {
+	rs.events = make(chan func(Observer), observerQueueSize)
+	rs.wg.Add(1)
+	go rs.runObserver(observer)
+}
+
+// runObserver delivers queued events, draining the queue once the instance is closed
+// @param observer - Observer receiving the events
+func (rs *RedisSnowflake) runObserver(observer Observer) {
+	defer rs.wg.Done()
+	for {
+		select {
+		case event := <-rs.events:
+			event(observer)
+		case <-rs.stop:
+			for {
+				select {
+				case event := <-rs.events:
+					event(observer)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// emit queues an event without blocking, doing nothing when no observer is set
+// @param event - func(Observer) invoking the callback
+func (rs *RedisSnowflake) emit(event func(Observer)) {
+	if rs.events == nil {
+		return
+	}
+	select {
+	case rs.events <- event:
+	default:
+	}
+}
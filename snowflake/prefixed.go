@@ -0,0 +1,182 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// prefixSeparator separates the type prefix from the encoded ID
+const prefixSeparator = "_"
+
+var (
+	// ErrInvalidPrefix represents a type prefix that is malformed or already registered
+	ErrInvalidPrefix = errors.New("invalid ID prefix")
+	// ErrUnknownPrefix represents a prefixed ID whose prefix is not registered
+	ErrUnknownPrefix = errors.New("unknown ID prefix")
+	// ErrPrefixMismatch represents a prefixed ID of another type than expected
+	ErrPrefixMismatch = errors.New("ID prefix mismatch")
+)
+
+// PrefixedID Parsed type-tagged ID such as "usr_0kt7P0RTL2t"
+type PrefixedID struct {
+	Prefix     string     // Type prefix, e.g. "usr"
+	ID         ID         // The snowflake ID
+	Components Components // Parts of the ID, decoded with the layout of the prefix's generator
+}
+
+// String Returns the prefixed form
+// @return string - prefix, separator and Base62 ID
+func (p PrefixedID) String() string {
+	return p.Prefix + prefixSeparator + p.ID.Base62()
+}
+
+// PrefixRegistry Maps type prefixes to the generators issuing their IDs
+type PrefixRegistry struct {
+	mu         sync.RWMutex
+	generators map[string]IDGenerator
+}
+
+// DefaultPrefixRegistry is the registry used by RegisterPrefix, NewPrefixed and ParsePrefixed
+var DefaultPrefixRegistry = NewPrefixRegistry()
+
+// NewPrefixRegistry Creates an empty registry
+// @return *PrefixRegistry - the created registry
+func NewPrefixRegistry() *PrefixRegistry {
+	return &PrefixRegistry{generators: make(map[string]IDGenerator)}
+}
+
+// validPrefix reports whether a prefix is a lowercase letter followed by lowercase letters or digits
+func validPrefix(prefix string) bool {
+	if prefix == "" || prefix[0] < 'a' || prefix[0] > 'z' {
+		return false
+	}
+	for i := 1; i < len(prefix); i++ {
+		c := prefix[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Register Assigns a generator to a type prefix; several prefixes may share one generator
+// @param prefix - string lowercase letter followed by lowercase letters or digits
+// @param generator - IDGenerator issuing the IDs of the type
+// @return error - ErrInvalidPrefix if the prefix is malformed or already registered
+func (r *PrefixRegistry) Register(prefix string, generator IDGenerator) error {
+	if !validPrefix(prefix) {
+		return fmt.Errorf("%w: %q must be a lowercase letter followed by lowercase letters or digits", ErrInvalidPrefix, prefix)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.generators[prefix]; ok {
+		return fmt.Errorf("%w: %q is already registered", ErrInvalidPrefix, prefix)
+	}
+	r.generators[prefix] = generator
+	return nil
+}
+
+// generator returns the generator of a prefix
+// @param prefix - string type prefix
+// @return IDGenerator - the registered generator
+// @return error - ErrUnknownPrefix if the prefix is not registered
+func (r *PrefixRegistry) generator(prefix string) (IDGenerator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	generator, ok := r.generators[prefix]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPrefix, prefix)
+	}
+	return generator, nil
+}
+
+// New Generates an ID of the type, formatted as <prefix>_<Base62 ID>
+// @param prefix - string registered type prefix
+// @return string - the prefixed ID
+// @return error - ErrUnknownPrefix, or any error of the generator
+func (r *PrefixRegistry) New(prefix string) (string, error) {
+	generator, err := r.generator(prefix)
+	if err != nil {
+		return "", err
+	}
+	id, err := generator.Generate()
+	if err != nil {
+		return "", err
+	}
+	return PrefixedID{Prefix: prefix, ID: ID(id)}.String(), nil
+}
+
+// Parse Splits a prefixed ID into its type and decoded ID
+// @param s - string prefixed ID
+// @return PrefixedID - the type, ID and components
+// @return error - ErrUnknownPrefix for an unregistered type, or ErrInvalidID for a malformed ID
+func (r *PrefixRegistry) Parse(s string) (PrefixedID, error) {
+	prefix, encoded, ok := strings.Cut(s, prefixSeparator)
+	if !ok {
+		return PrefixedID{}, fmt.Errorf("%w: %q has no prefix", ErrInvalidID, s)
+	}
+	generator, err := r.generator(prefix)
+	if err != nil {
+		return PrefixedID{}, err
+	}
+	id, err := Base62.Decode(encoded)
+	if err != nil {
+		return PrefixedID{}, err
+	}
+	layout := DefaultLayout
+	if l, ok := generator.(interface{ Layout() Layout }); ok {
+		layout = l.Layout()
+	}
+	components, err := layout.Decode(int64(id))
+	if err != nil {
+		return PrefixedID{}, err
+	}
+	return PrefixedID{Prefix: prefix, ID: id, Components: components}, nil
+}
+
+// ParseAs Parses a prefixed ID that must be of the given type
+// @param prefix - string expected type prefix
+// @param s - string prefixed ID
+// @return PrefixedID - the type, ID and components
+// @return error - ErrPrefixMismatch for an ID of another type, or any error of Parse
+func (r *PrefixRegistry) ParseAs(prefix, s string) (PrefixedID, error) {
+	if got, _, ok := strings.Cut(s, prefixSeparator); ok && got != prefix {
+		return PrefixedID{}, fmt.Errorf("%w: expected %q, got %q", ErrPrefixMismatch, prefix, got)
+	}
+	return r.Parse(s)
+}
+
+// RegisterPrefix Assigns a generator to a type prefix in DefaultPrefixRegistry
+// @param prefix - string lowercase letter followed by lowercase letters or digits
+// @param generator - IDGenerator issuing the IDs of the type
+// @return error - ErrInvalidPrefix if the prefix is malformed or already registered
+func RegisterPrefix(prefix string, generator IDGenerator) error {
+	return DefaultPrefixRegistry.Register(prefix, generator)
+}
+
+// NewPrefixed Generates an ID of a type registered in DefaultPrefixRegistry, e.g. "usr_0kt7P0RTL2t"
+// @param prefix - string registered type prefix
+// @return string - the prefixed ID
+// @return error - ErrUnknownPrefix, or any error of the generator
+func NewPrefixed(prefix string) (string, error) {
+	return DefaultPrefixRegistry.New(prefix)
+}
+
+// ParsePrefixed Parses a prefixed ID of a type registered in DefaultPrefixRegistry
+// @param s - string prefixed ID
+// @return PrefixedID - the type, ID and components
+// @return error - ErrUnknownPrefix or ErrInvalidID
+func ParsePrefixed(s string) (PrefixedID, error) {
+	return DefaultPrefixRegistry.Parse(s)
+}
+
+// ParsePrefixedAs Parses a prefixed ID that must be of the given type registered in DefaultPrefixRegistry
+// @param prefix - string expected type prefix
+// @param s - string prefixed ID
+// @return PrefixedID - the type, ID and components
+// @return error - ErrPrefixMismatch, ErrUnknownPrefix or ErrInvalidID
+func ParsePrefixedAs(prefix, s string) (PrefixedID, error) {
+	return DefaultPrefixRegistry.ParseAs(prefix, s)
+}
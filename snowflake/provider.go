@@ -0,0 +1,185 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultDatacenterEnv is the default environment variable read by EnvProvider for the datacenter ID
+	DefaultDatacenterEnv = "SNOWREDIS_DATACENTER_ID"
+	// DefaultWorkerEnv is the default environment variable read by EnvProvider for the worker ID
+	DefaultWorkerEnv = "SNOWREDIS_WORKER_ID"
+)
+
+var (
+	// ErrNoIPv4Address represents the absence of a usable IPv4 address
+	ErrNoIPv4Address = errors.New("no non-loopback IPv4 address found")
+	// ErrNoOrdinal represents a hostname without a StatefulSet ordinal suffix
+	ErrNoOrdinal = errors.New("hostname has no StatefulSet ordinal suffix")
+)
+
+// WorkerIDProvider Interface for deriving datacenter and worker IDs without Redis
+type WorkerIDProvider interface {
+	// NodeID derives the datacenter ID and worker ID for the given layout
+	// @param layout - Layout the IDs will be validated against
+	// @return int64 - the derived datacenter ID
+	// @return int64 - the derived worker ID
+	// @return error - any error that occurred during derivation
+	NodeID(layout Layout) (int64, int64, error)
+}
+
+// IPv4Provider Derives the node from the low bits of the primary IPv4 address
+type IPv4Provider struct {
+	IP net.IP // Address to use, the first non-loopback IPv4 interface address when nil
+}
+
+// NodeID derives the node from the low DatacenterBits+WorkerBits bits of the IPv4 address
+// @param layout - Layout the IDs will be validated against
+// @return int64 - the derived datacenter ID
+// @return int64 - the derived worker ID
+// @return error - ErrNoIPv4Address if no IPv4 address is available
+func (p IPv4Provider) NodeID(layout Layout) (int64, int64, error) {
+	ip := p.IP
+	if ip == nil {
+		var err error
+		if ip, err = primaryIPv4(); err != nil {
+			return 0, 0, err
+		}
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return 0, 0, ErrNoIPv4Address
+	}
+	n := int64(ip4[0])<<24 | int64(ip4[1])<<16 | int64(ip4[2])<<8 | int64(ip4[3])
+	datacenterID, workerID := layout.splitNode(n & layout.maxNode())
+	return datacenterID, workerID, nil
+}
+
+// primaryIPv4 returns the first non-loopback IPv4 address of the host
+// @return net.IP - the IPv4 address
+// @return error - ErrNoIPv4Address if none is found
+func primaryIPv4() (net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+	}
+	return nil, ErrNoIPv4Address
+}
+
+// StatefulSetProvider Derives the node from the ordinal suffix of a StatefulSet hostname (e.g. "web-3")
+type StatefulSetProvider struct {
+	Hostname string // Hostname to parse, os.Hostname() when empty
+}
+
+// NodeID derives the node from the hostname ordinal, the worker ID takes the low bits
+// @param layout - Layout the IDs will be validated against
+// @return int64 - the derived datacenter ID
+// @return int64 - the derived worker ID
+// @return error - ErrNoOrdinal if the hostname does not end with "-<n>"
+func (p StatefulSetProvider) NodeID(layout Layout) (int64, int64, error) {
+	hostname, err := resolveHostname(p.Hostname)
+	if err != nil {
+		return 0, 0, err
+	}
+	idx := strings.LastIndexByte(hostname, '-')
+	if idx < 0 {
+		return 0, 0, fmt.Errorf("%w: %q", ErrNoOrdinal, hostname)
+	}
+	ordinal, err := strconv.ParseInt(hostname[idx+1:], 10, 64)
+	if err != nil || ordinal < 0 {
+		return 0, 0, fmt.Errorf("%w: %q", ErrNoOrdinal, hostname)
+	}
+	datacenterID, workerID := layout.splitNode(ordinal)
+	return datacenterID, workerID, nil
+}
+
+// EnvProvider Reads the datacenter ID and worker ID from environment variables
+type EnvProvider struct {
+	DatacenterVar string // Datacenter ID variable, DefaultDatacenterEnv when empty (unset means 0)
+	WorkerVar     string // Worker ID variable, DefaultWorkerEnv when empty (must be set)
+}
+
+// NodeID reads the node from the environment
+// @param layout - Layout the IDs will be validated against
+// @return int64 - the datacenter ID
+// @return int64 - the worker ID
+// @return error - any error that occurred while reading or parsing the variables
+func (p EnvProvider) NodeID(_ Layout) (int64, int64, error) {
+	datacenterVar, workerVar := p.DatacenterVar, p.WorkerVar
+	if datacenterVar == "" {
+		datacenterVar = DefaultDatacenterEnv
+	}
+	if workerVar == "" {
+		workerVar = DefaultWorkerEnv
+	}
+
+	var datacenterID int64
+	if value, ok := os.LookupEnv(datacenterVar); ok {
+		id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s: %w", datacenterVar, err)
+		}
+		datacenterID = id
+	}
+
+	value, ok := os.LookupEnv(workerVar)
+	if !ok {
+		return 0, 0, fmt.Errorf("environment variable %s is not set", workerVar)
+	}
+	workerID, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid %s: %w", workerVar, err)
+	}
+	return datacenterID, workerID, nil
+}
+
+// HostnameHashProvider Derives the node from an FNV-1a hash of the hostname
+type HostnameHashProvider struct {
+	Hostname string // Hostname to hash, os.Hostname() when empty
+}
+
+// NodeID derives the node from the low DatacenterBits+WorkerBits bits of the hostname hash
+// @param layout - Layout the IDs will be validated against
+// @return int64 - the derived datacenter ID
+// @return int64 - the derived worker ID
+// @return error - any error that occurred while resolving the hostname
+func (p HostnameHashProvider) NodeID(layout Layout) (int64, int64, error) {
+	hostname, err := resolveHostname(p.Hostname)
+	if err != nil {
+		return 0, 0, err
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(hostname))
+	datacenterID, workerID := layout.splitNode(int64(h.Sum64() & uint64(layout.maxNode())))
+	return datacenterID, workerID, nil
+}
+
+// resolveHostname returns the given hostname or the host's name when empty
+// @param hostname - string hostname override
+// @return string - the hostname to use
+// @return error - any error returned by os.Hostname
+func resolveHostname(hostname string) (string, error) {
+	if hostname != "" {
+		return hostname, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %w", err)
+	}
+	return hostname, nil
+}
@@ -0,0 +1,56 @@
+package snowflake
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// SequenceStart Decides where the sequence of each new millisecond begins
+type SequenceStart int
+
+const (
+	// SequenceStartZero starts every millisecond at sequence 0 (default)
+	SequenceStartZero SequenceStart = iota
+	// SequenceStartRandom starts every millisecond at a random sequence
+	SequenceStartRandom
+	// SequenceStartRotating starts every millisecond one past the previous start
+	SequenceStartRotating
+)
+
+// String Returns the name of the sequence start mode
+// @return string - the mode name
+func (s SequenceStart) String() string {
+	switch s {
+	case SequenceStartZero:
+		return "zero"
+	case SequenceStartRandom:
+		return "random"
+	case SequenceStartRotating:
+		return "rotating"
+	default:
+		return fmt.Sprintf("SequenceStart(%d)", int(s))
+	}
+}
+
+// startSequence begins the sequence of a new millisecond
+// With a non-zero start the sequence wraps around past the maximum and the millisecond
+// is exhausted when it comes back to its start, so all values stay unique
+// Must be called with the node lock held
+func (rs *RedisSnowflake) startSequence() {
+	switch rs.sequenceStart {
+	case SequenceStartRandom:
+		rs.node.sequenceStart = rs.rand.Int63() & rs.layout.MaxSequence()
+	case SequenceStartRotating:
+		rs.node.sequenceStart = (rs.node.sequenceStart + 1) & rs.layout.MaxSequence()
+	default:
+		rs.node.sequenceStart = 0
+	}
+	rs.node.sequence = rs.node.sequenceStart
+}
+
+// newRand returns a random source for sequence starts
+// @param seed - int64 seed
+// @return *rand.Rand - the random source, only used with the node lock held
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
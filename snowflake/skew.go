@@ -0,0 +1,110 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+)
+
+// DefaultClockSkewInterval is how often the clock is compared with Redis TIME
+const DefaultClockSkewInterval = 30 * time.Second
+
+// ErrClockSkew represents a local clock too far from the Redis server clock
+var ErrClockSkew = errors.New("local clock skew exceeds the allowed maximum")
+
+// measureClockSkew compares the local clock with Redis TIME
+// @param ctx - context for the operation
+// @param client - redis.TimeClient to query
+// @param clock - Clock providing the local time
+// @return time.Duration - local time minus Redis time, positive when the local clock is fast
+// @return error - any error that occurred while querying Redis
+func measureClockSkew(ctx context.Context, client redis.TimeClient, clock Clock) (time.Duration, error) {
+	before := clock.Now()
+	serverTime, err := client.Time(ctx)
+	if err != nil {
+		return 0, err
+	}
+	after := clock.Now()
+	// Assume the server read its clock halfway through the round trip
+	local := before.Add(after.Sub(before) / 2)
+	return local.Sub(serverTime), nil
+}
+
+// absDuration returns the absolute value of a duration
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// checkClockSkew measures the skew before any Redis allocation takes place
+// @return time.Duration - the measured skew
+// @return error - ErrClockSkew past the maximum, or an error if Redis TIME is unavailable
+func (builder *RedisSnowflakeBuilder) checkClockSkew() (time.Duration, error) {
+	client, ok := builder.client.(redis.TimeClient)
+	if !ok {
+		return 0, fmt.Errorf("%w: clock skew monitoring requires TIME", ErrUnsupportedClient)
+	}
+	skew, err := measureClockSkew(context.Background(), client, builder.clock)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Redis time: %w", err)
+	}
+	if absDuration(skew) > builder.skewMax {
+		return skew, fmt.Errorf("%w: %v", ErrClockSkew, skew)
+	}
+	if absDuration(skew) > builder.skewWarn {
+		builder.logger.Printf("snowflake: local clock skew %v exceeds warning threshold %v", skew, builder.skewWarn)
+	}
+	return skew, nil
+}
+
+// startSkewMonitor records the startup skew and starts periodic checks
+// @param rs - *RedisSnowflake to monitor
+// @param skew - time.Duration measured by checkClockSkew
+func (builder *RedisSnowflakeBuilder) startSkewMonitor(rs *RedisSnowflake, skew time.Duration) {
+	client := builder.client.(redis.TimeClient)
+	rs.clockSkew.Store(int64(skew))
+
+	rs.wg.Add(1)
+	go rs.runSkewMonitor(client, builder.skewWarn, builder.skewMax, builder.skewInterval, builder.logger)
+}
+
+// runSkewMonitor periodically compares the local clock with Redis TIME until the instance is cleaned up
+// Generation is refused while the skew exceeds maxSkew and resumes once it is back within bounds
+// @param client - redis.TimeClient to query
+// @param warn - time.Duration past which a warning is logged
+// @param maxSkew - time.Duration past which generation is refused
+// @param interval - time.Duration between checks
+// @param logger - Logger receiving warnings
+func (rs *RedisSnowflake) runSkewMonitor(client redis.TimeClient, warn, maxSkew, interval time.Duration, logger Logger) {
+	defer rs.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rs.stop:
+			return
+		case <-ticker.C:
+			start := time.Now()
+			skew, err := measureClockSkew(rs.ctx, client, rs.clock)
+			rs.metrics.observeRedis(start, err)
+			if err != nil {
+				logger.Printf("snowflake: failed to read Redis time: %v", err)
+				continue
+			}
+			rs.clockSkew.Store(int64(skew))
+			exceeded := absDuration(skew) > maxSkew
+			rs.skewExceeded.Store(exceeded)
+			if exceeded {
+				logger.Printf("snowflake: local clock skew %v exceeds maximum %v, generation refused", skew, maxSkew)
+			} else if absDuration(skew) > warn {
+				logger.Printf("snowflake: local clock skew %v exceeds warning threshold %v", skew, warn)
+			}
+		}
+	}
+}
@@ -2,8 +2,11 @@ package snowflake
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sunquakes/snowredis/redis"
@@ -20,9 +23,9 @@ const (
 	NoAllocationFlag = false
 	// ZeroValue represents zero value
 	ZeroValue = 0
-	// MaxDatacenterIDPlusOne is used to limit datacenter ID to 5 bits
+	// MaxDatacenterIDPlusOne is the number of datacenter IDs in DefaultLayout
 	MaxDatacenterIDPlusOne = 32
-	// MaxWorkerIDPlusOne is used to limit worker ID to 5 bits
+	// MaxWorkerIDPlusOne is the number of worker IDs in DefaultLayout
 	MaxWorkerIDPlusOne = 32
 )
 
@@ -32,7 +35,29 @@ type RedisSnowflake struct {
 	redisClient   redis.Client
 	ctx           context.Context
 	lastTimestamp int64
-	strictMode    bool // Strict mode, use Redis assistance to prevent duplicates
+	strictMode    bool           // Strict mode, use Redis assistance to prevent duplicates
+	stop          chan struct{}  // Closed by Cleanup to stop background goroutines
+	wg            sync.WaitGroup // Tracks background goroutines
+	closeOnce     sync.Once
+	closed        atomic.Bool  // Set by Close, after which generation is refused
+	clockSkew     atomic.Int64 // Last measured clock skew in nanoseconds
+	skewExceeded  atomic.Bool  // Whether the last measured skew exceeded the maximum
+	clock         Clock
+	// Behavior when the sequence of a millisecond is used up
+	exhaustionPolicy    ExhaustionPolicy
+	borrowLimit         time.Duration
+	sequenceExhaustions atomic.Uint64
+	sequenceStart       SequenceStart
+	rand                *rand.Rand // Source for random sequence starts, guarded by the node lock
+	layout              Layout
+	lease               atomic.Pointer[lease] // Worker slot lease, nil when leasing is disabled
+	fencing             bool                  // Whether generation stops while the lease is not confirmed
+	fenced              atomic.Bool           // Set while the lease is lost and no slot has been reacquired
+	keys                keyspace
+	metrics             *metrics
+	watermarkStore      WatermarkStore // Store of the high-water marks, nil without persistence
+	watermarkMaxWait    time.Duration
+	events              chan func(Observer) // Queue of the observer goroutine, nil without an observer
 }
 
 // RedisSnowflakeBuilder Builder for Redis-based snowflake instance
@@ -41,11 +66,43 @@ type RedisSnowflakeBuilder struct {
 	datacenterID int64
 	workerID     int64
 	strictMode   bool // Whether to use strict mode with Redis assistance
+	provider     WorkerIDProvider
+	// High-water mark persistence guarding against restart after a clock rollback
+	watermarkStore    WatermarkStore
+	watermarkInterval time.Duration
+	watermarkMaxWait  time.Duration
+	// Clock skew monitoring against Redis TIME
+	skewWarn     time.Duration
+	skewMax      time.Duration
+	skewInterval time.Duration
+	logger       Logger
+	clock        Clock
+	// Sequence exhaustion handling
+	exhaustionPolicy ExhaustionPolicy
+	borrowLimit      time.Duration
+	sequenceStart    SequenceStart
+	layout           Layout
+	// Remaining time the layout must last for Build to succeed, 0 to skip the check
+	minLifetime time.Duration
+	// Worker slot leasing
+	leaseTTL    time.Duration
+	leaseHolder string
+	fencing     bool
+	keys        keyspace
+	observer    Observer
 }
 
 // NewBuilder Creates a new RedisSnowflakeBuilder instance
 func NewBuilder() *RedisSnowflakeBuilder {
-	return &RedisSnowflakeBuilder{}
+	return &RedisSnowflakeBuilder{
+		watermarkInterval: DefaultWatermarkInterval,
+		watermarkMaxWait:  DefaultWatermarkMaxWait,
+		skewInterval:      DefaultClockSkewInterval,
+		logger:            log.Default(),
+		clock:             systemClock{},
+		borrowLimit:       DefaultBorrowLimit,
+		layout:            DefaultLayout,
+	}
 }
 
 // SetRedisClient Sets the Redis client for the builder
@@ -80,13 +137,260 @@ func (builder *RedisSnowflakeBuilder) SetStrictMode(strict bool) *RedisSnowflake
 	return builder
 }
 
+// SetWorkerIDProvider Sets the strategy used to derive datacenter and worker IDs without Redis
+// The provider takes precedence over Redis allocation but not over manually set IDs
+// @param provider - WorkerIDProvider such as IPv4Provider, StatefulSetProvider, EnvProvider or HostnameHashProvider
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetWorkerIDProvider(provider WorkerIDProvider) *RedisSnowflakeBuilder {
+	builder.provider = provider
+	return builder
+}
+
+// SetWatermarkStore Enables persistence of the last-issued timestamp of the worker slot
+// Build waits until the clock passes the stored mark, so a restarted process never reissues IDs
+// @param store - WatermarkStore such as RedisWatermarkStore or FileWatermarkStore
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetWatermarkStore(store WatermarkStore) *RedisSnowflakeBuilder {
+	builder.watermarkStore = store
+	return builder
+}
+
+// SetWatermarkInterval Sets how often the high-water mark is persisted
+// @param interval - time.Duration between writes (default DefaultWatermarkInterval)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetWatermarkInterval(interval time.Duration) *RedisSnowflakeBuilder {
+	builder.watermarkInterval = interval
+	return builder
+}
+
+// SetWatermarkMaxWait Sets how long Build waits for the clock to pass the stored high-water mark
+// @param maxWait - time.Duration to wait before failing with ErrClockBehindWatermark (0 refuses immediately)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetWatermarkMaxWait(maxWait time.Duration) *RedisSnowflakeBuilder {
+	builder.watermarkMaxWait = maxWait
+	return builder
+}
+
+// SetClockSkewLimits Enables monitoring of the local clock against Redis TIME
+// Past warn a warning is logged; past maxSkew Build refuses to allocate and Generate returns ErrClockSkew
+// @param warn - time.Duration of skew that triggers a warning
+// @param maxSkew - time.Duration of skew that refuses allocation and generation (0 disables monitoring)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetClockSkewLimits(warn, maxSkew time.Duration) *RedisSnowflakeBuilder {
+	builder.skewWarn = warn
+	builder.skewMax = maxSkew
+	return builder
+}
+
+// SetClockSkewInterval Sets how often the clock is compared with Redis TIME
+// @param interval - time.Duration between checks (default DefaultClockSkewInterval)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetClockSkewInterval(interval time.Duration) *RedisSnowflakeBuilder {
+	builder.skewInterval = interval
+	return builder
+}
+
+// SetLogger Sets the logger receiving warnings from background checks
+// @param logger - Logger such as *log.Logger (default log.Default())
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetLogger(logger Logger) *RedisSnowflakeBuilder {
+	builder.logger = logger
+	return builder
+}
+
+// SetClock Sets the time source used for ID timestamps, mainly for deterministic tests
+// @param clock - Clock to read the current time from (default is the system clock)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetClock(clock Clock) *RedisSnowflakeBuilder {
+	builder.clock = clock
+	return builder
+}
+
+// SetExhaustionPolicy Sets what happens when all sequence numbers of a millisecond are used up
+// @param policy - ExhaustionPolicy (default ExhaustionSpin)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetExhaustionPolicy(policy ExhaustionPolicy) *RedisSnowflakeBuilder {
+	builder.exhaustionPolicy = policy
+	return builder
+}
+
+// SetBorrowLimit Sets how far ahead of the clock ExhaustionBorrow may issue IDs
+// @param limit - time.Duration of future milliseconds that may be borrowed (default DefaultBorrowLimit)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetBorrowLimit(limit time.Duration) *RedisSnowflakeBuilder {
+	builder.borrowLimit = limit
+	return builder
+}
+
+// SetSequenceStart Sets where the sequence of each millisecond begins
+// A random or rotating start spreads low-traffic IDs evenly for id % N sharding,
+// at the cost of IDs within one millisecond no longer being increasing
+// @param start - SequenceStart (default SequenceStartZero)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetSequenceStart(start SequenceStart) *RedisSnowflakeBuilder {
+	builder.sequenceStart = start
+	return builder
+}
+
+// SetLayout Sets how the bits of an ID are divided and the epoch timestamps count from
+// Start from DefaultLayout and change the fields you need
+// @param layout - Layout to generate IDs with (default DefaultLayout)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetLayout(layout Layout) *RedisSnowflakeBuilder {
+	builder.layout = layout
+	return builder
+}
+
+// SetEpoch Sets the epoch timestamps count from, keeping the layout's bit allocation
+// @param epoch - int64 milliseconds since the Unix epoch (default Epoch)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetEpoch(epoch int64) *RedisSnowflakeBuilder {
+	builder.layout.Epoch = epoch
+	return builder
+}
+
+// SetMinLifetime Makes Build refuse a layout and epoch that run out of timestamps within the given time
+// @param minLifetime - time.Duration the layout must still last (default 0, no check)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetMinLifetime(minLifetime time.Duration) *RedisSnowflakeBuilder {
+	builder.minLifetime = minLifetime
+	return builder
+}
+
+// SetLeaseTTL Enables leasing of the worker slot in Redis under <prefix>:slot:<datacenter>:<worker>
+// The lease is renewed every third of the TTL and released by Cleanup; with auto-allocation a free
+// slot is picked, with manual or provided IDs Build fails with ErrSlotTaken if the slot is held
+// @param ttl - time.Duration the slot stays leased without renewal (0 disables leasing)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetLeaseTTL(ttl time.Duration) *RedisSnowflakeBuilder {
+	builder.leaseTTL = ttl
+	return builder
+}
+
+// SetLeaseHolder Sets the name recorded as holder of the worker slot lease
+// @param holder - string holder name (default hostname/pid/random)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetLeaseHolder(holder string) *RedisSnowflakeBuilder {
+	builder.leaseHolder = holder
+	return builder
+}
+
+// SetFencing Sets whether generation stops with ErrLeaseLost once the slot lease cannot be confirmed before expiry
+// The instance then leases a slot again, possibly a different one, before it resumes; Build returns
+// ErrFencingWithoutLease unless SetRedisClient and SetLeaseTTL are set as well
+// @param enabled - bool indicating whether to fence (default false)
+// @return *RedisSnowflakeBuilder - the builder instance for method chaining
+func (builder *RedisSnowflakeBuilder) SetFencing(enabled bool) *RedisSnowflakeBuilder {
+	builder.fencing = enabled
+	return builder
+}
+
+// SetKeyPrefix Sets the prefix of every Redis key the instance uses, so independent ID domains can share one Redis
+// Wrap the prefix in braces, e.g. "{orders}", to keep all keys of a domain in one Redis Cluster hash slot
+// A RedisWatermarkStore without its own prefix uses this one as well
+// @param prefix - string key prefix (default DefaultKeyPrefix)
+// @return *RedisSnowflakeBuilder - the builder instance for chaining
+func (builder *RedisSnowflakeBuilder) SetKeyPrefix(prefix string) *RedisSnowflakeBuilder {
+	builder.keys = keyspace{prefix: prefix}
+	return builder
+}
+
+// SetObserver Sets the observer notified of rollbacks, exhaustion, lease problems and strict-mode fallbacks
+// @param observer - Observer receiving the events, embed NopObserver to implement only some callbacks
+// @return *RedisSnowflakeBuilder - the builder instance for method chaining
+func (builder *RedisSnowflakeBuilder) SetObserver(observer Observer) *RedisSnowflakeBuilder {
+	builder.observer = observer
+	return builder
+}
+
 // Build Creates and returns a RedisSnowflake instance based on the configured parameters
 // @return *RedisSnowflake - the configured snowflake instance
 // @return error - any error that occurred during construction
 func (builder *RedisSnowflakeBuilder) Build() (*RedisSnowflake, error) {
+	if err := builder.validate(); err != nil {
+		return nil, err
+	}
+
+	// Check the clock against Redis before any slot is allocated
+	var skew time.Duration
+	if builder.skewMax > 0 {
+		var err error
+		if skew, err = builder.checkClockSkew(); err != nil {
+			return nil, err
+		}
+	}
+
+	rs, err := builder.build()
+	if err != nil {
+		return nil, err
+	}
+	if builder.observer != nil {
+		rs.startObserver(builder.observer)
+	}
+
+	if builder.leaseTTL > 0 && rs.redisClient != nil && rs.lease.Load() == nil {
+		if err := builder.leaseSlot(rs); err != nil {
+			rs.Cleanup()
+			return nil, err
+		}
+	}
+	if rs.lease.Load() != nil {
+		rs.fencing = builder.fencing
+		rs.wg.Add(1)
+		go rs.runLease(builder.logger)
+	}
+
+	if builder.watermarkStore != nil {
+		if err := builder.restoreWatermark(rs); err != nil {
+			rs.Cleanup()
+			return nil, err
+		}
+	}
+	if builder.skewMax > 0 {
+		builder.startSkewMonitor(rs, skew)
+	}
+	datacenterID, workerID := rs.node.ids()
+	rs.emit(func(o Observer) { o.OnAllocated(datacenterID, workerID) })
+	if rs.strictMode && rs.redisClient == nil {
+		rs.emit(func(o Observer) { o.OnStrictFallback(ErrStrictWithoutRedis) })
+	}
+	return rs, nil
+}
+
+// validate checks the settings before anything is allocated
+// @return error - ErrInvalidLayout, ErrInvalidInterval or ErrLifetimeTooShort describing the problem
+func (builder *RedisSnowflakeBuilder) validate() error {
+	if err := builder.layout.Validate(); err != nil {
+		return err
+	}
+	if builder.watermarkStore != nil && builder.watermarkInterval <= 0 {
+		return fmt.Errorf("%w: watermark interval %v", ErrInvalidInterval, builder.watermarkInterval)
+	}
+	if builder.skewMax > 0 && builder.skewInterval <= 0 {
+		return fmt.Errorf("%w: clock skew interval %v", ErrInvalidInterval, builder.skewInterval)
+	}
+	if builder.fencing && (builder.client == nil || builder.leaseTTL <= 0) {
+		return ErrFencingWithoutLease
+	}
+	return builder.layout.checkLifetime(builder.clock.Now(), builder.minLifetime)
+}
+
+// build creates the instance based on the priority of the configured parameters
+// @return *RedisSnowflake - the configured snowflake instance
+// @return error - any error that occurred during construction
+func (builder *RedisSnowflakeBuilder) build() (*RedisSnowflake, error) {
+	if builder.provider != nil && !builder.hasManualIDs() {
+		// Derive IDs from the configured provider
+		return builder.createProvidedInstance()
+	}
+
 	// Determine how to build the instance based on priority
 	datacenterID, workerID, useRedisAllocation := builder.determineConfiguration()
 	if useRedisAllocation {
+		if builder.leaseTTL > 0 {
+			// Lease a free worker slot
+			return builder.createLeasedInstance(builder.client)
+		}
 		// Use Redis for automatic ID allocation
 		return builder.createRedisAllocatedInstance(builder.client)
 	} else if builder.client != nil {
@@ -103,7 +407,7 @@ func (builder *RedisSnowflakeBuilder) Build() (*RedisSnowflake, error) {
 // @return bool - flag indicating whether to use Redis allocation
 func (builder *RedisSnowflakeBuilder) determineConfiguration() (int64, int64, bool) {
 	// 1. If datacenterID and workerID (non-zero) are set, prioritize manual values
-	if builder.datacenterID != ZeroValue && builder.workerID != ZeroValue {
+	if builder.hasManualIDs() {
 		return builder.datacenterID, builder.workerID, NoAllocationFlag
 	} else if builder.client != nil {
 		// 2. If Redis client is set but no manual IDs, allocate automatically via Redis
@@ -113,6 +417,12 @@ func (builder *RedisSnowflakeBuilder) determineConfiguration() (int64, int64, bo
 	return DefaultDatacenterID, DefaultWorkerID, NoAllocationFlag // Use default values
 }
 
+// hasManualIDs reports whether both datacenter ID and worker ID were set manually
+// @return bool - true if both IDs are non-zero
+func (builder *RedisSnowflakeBuilder) hasManualIDs() bool {
+	return builder.datacenterID != ZeroValue && builder.workerID != ZeroValue
+}
+
 // generateLocally generates an ID locally without Redis coordination
 // @return int64 - the generated unique ID
 // @return error - any error that occurred during generation (e.g. clock rollback)
@@ -120,64 +430,172 @@ func (rs *RedisSnowflake) generateLocally() (int64, error) {
 	rs.node.Lock()
 	defer rs.node.Unlock()
 
-	timestamp := currentTimeMillis()
+	id, err := rs.nextLocalID()
+	if err == nil {
+		rs.metrics.generated.Add(1)
+	}
+	return id, err
+}
+
+// nextLocalID generates the next local ID, must be called with the node lock held
+// @return int64 - the generated unique ID
+// @return error - any error that occurred during generation (e.g. clock rollback)
+func (rs *RedisSnowflake) nextLocalID() (int64, error) {
+	timestamp, err := rs.nextTimestamp()
+	if err != nil {
+		return 0, err
+	}
+
+	// Calculate ID
+	id, err := rs.layout.compose(timestamp, rs.node.datacenterID, rs.node.workerID, rs.node.sequence)
+	if err != nil {
+		return 0, err
+	}
+
+	rs.node.lastTimestamp = timestamp
+	return id, nil
+}
+
+// nextTimestamp advances the node to the next sequence number, must be called with the node lock held
+// The caller records the returned timestamp as the last timestamp once the ID is issued
+// @return int64 - the millisecond of the next ID, its sequence number is rs.node.sequence
+// @return error - ErrClockRollback, or ErrOverFlow under ExhaustionError
+func (rs *RedisSnowflake) nextTimestamp() (int64, error) {
+	timestamp := rs.currentTimeMillis()
 
 	// If timestamp is less than last timestamp, clock rollback occurred
 	if timestamp < rs.node.lastTimestamp {
-		return 0, errors.New("clock rollback error")
+		if !rs.withinBorrowed(timestamp, rs.node.lastTimestamp) {
+			rs.metrics.rollbacks.Add(1)
+			drift := time.Duration(rs.node.lastTimestamp-timestamp) * time.Millisecond
+			rs.emit(func(o Observer) { o.OnClockRollback(drift) })
+			return 0, ErrClockRollback
+		}
+		// Keep issuing from the borrowed millisecond
+		timestamp = rs.node.lastTimestamp
 	}
 
 	// If generating in the same millisecond, increment sequence number
 	if rs.node.lastTimestamp == timestamp {
-		rs.node.sequence = (rs.node.sequence + 1) & maxSequence
-		// If sequence number overflows, wait for next millisecond
-		if rs.node.sequence == 0 {
-			for timestamp <= rs.node.lastTimestamp {
-				timestamp = currentTimeMillis()
+		rs.node.sequence = (rs.node.sequence + 1) & rs.layout.MaxSequence()
+		// If sequence number wraps back to its start, move on according to the exhaustion policy
+		if rs.node.sequence == rs.node.sequenceStart {
+			var err error
+			if timestamp, err = rs.nextMillis(rs.node.lastTimestamp); err != nil {
+				rs.node.sequence = (rs.node.sequenceStart - 1) & rs.layout.MaxSequence()
+				return 0, err
 			}
+			rs.startSequence()
 		}
 	} else {
-		// Different millisecond, reset sequence number
-		rs.node.sequence = 0
+		// Different millisecond, restart sequence number
+		rs.startSequence()
 	}
+	return timestamp, nil
+}
 
-	rs.node.lastTimestamp = timestamp
+// GenerateN Generates n unique IDs in one call, holding the node lock once in local mode
+// @param n - int number of IDs to generate
+// @return []int64 - the generated IDs in generation order
+// @return error - any error that occurred during generation
+func (rs *RedisSnowflake) GenerateN(n int) ([]int64, error) {
+	return rs.GenerateNContext(rs.ctx, n)
+}
 
-	// Calculate ID
-	id := ((timestamp - Epoch) << timestampShift) |
-		(rs.node.datacenterID << datacenterShift) |
-		(rs.node.workerID << workerShift) |
-		rs.node.sequence
+// GenerateNContext Generates n unique IDs like GenerateN, bounding the strict-mode Redis calls by ctx
+// @param ctx - context for the operation
+// @param n - int number of IDs to generate
+// @return []int64 - the generated IDs in generation order
+// @return error - any error that occurred during generation, or the context error once ctx is done
+func (rs *RedisSnowflake) GenerateNContext(ctx context.Context, n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := rs.ready(); err != nil {
+		return nil, err
+	}
 
-	return id, nil
+	ids := make([]int64, 0, n)
+	if rs.strictMode && rs.redisClient != nil {
+		for len(ids) < n {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			id, err := rs.generateWithRedisAssistance(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	rs.node.Lock()
+	defer rs.node.Unlock()
+	for len(ids) < n {
+		id, err := rs.nextLocalID()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rs.metrics.generated.Add(uint64(n))
+	return ids, nil
+}
+
+// ready checks that the instance may issue IDs
+// @return error - ErrClosed, ErrClockSkew or ErrLeaseLost
+func (rs *RedisSnowflake) ready() error {
+	if rs.closed.Load() {
+		return ErrClosed
+	}
+	if rs.skewExceeded.Load() {
+		return fmt.Errorf("%w: %v", ErrClockSkew, time.Duration(rs.clockSkew.Load()))
+	}
+	return rs.fence()
 }
 
 // generateWithRedisAssistance generates an ID using Redis assistance to ensure global uniqueness
+// @param ctx - context for the Redis calls
 // @return int64 - the generated unique ID
 // @return error - any error that occurred during generation
-func (rs *RedisSnowflake) generateWithRedisAssistance() (int64, error) {
+func (rs *RedisSnowflake) generateWithRedisAssistance(ctx context.Context) (int64, error) {
 	// Try multiple times to generate an ID until we successfully obtain a unique one
 	maxRetries := 10
+	datacenterID, workerID := rs.node.ids()
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		timestamp := currentTimeMillis()
+		timestamp, err := rs.strictTimestamp()
+		if err != nil {
+			return 0, err
+		}
 
 		// Combine timestamp, datacenterID, workerID and attempt count to form a unique ID
-		id := ((timestamp - Epoch) << timestampShift) |
-			(rs.node.datacenterID << datacenterShift) |
-			(rs.node.workerID << workerShift) |
-			((timestamp + int64(attempt)) & maxSequence) // Use timestamp+attempt count as sequence part
+		// Use timestamp+attempt count as sequence part
+		sequence := (timestamp + int64(attempt)) & rs.layout.MaxSequence()
+		id, err := rs.layout.compose(timestamp, datacenterID, workerID, sequence)
+		if err != nil {
+			return 0, err
+		}
 
 		// Try to record this ID in Redis using distributed lock mechanism
-		key := fmt.Sprintf("snowflake:id:%d", id)
-		lockAcquired, err := rs.redisClient.SetNX(rs.ctx, key, "1", time.Hour) // Lock for 1 hour
+		key := rs.keys.id(id)
+		start := time.Now()
+		lockAcquired, err := rs.redisClient.SetNX(ctx, key, "1", time.Hour) // Lock for 1 hour
+		rs.metrics.observeRedis(start, err)
 		if err != nil {
 			return 0, fmt.Errorf("failed to acquire lock for ID generation: %w", err)
 		}
 
 		if lockAcquired {
 			// Successfully acquired lock, ID is unique
+			rs.issuedAt(timestamp)
+			rs.metrics.generated.Add(1)
 			return id, nil
 		}
+		rs.metrics.strictRetries.Add(1)
 
 		// If unable to acquire lock, ID is already taken, retry
 		// Consider brief delay to reduce contention
@@ -189,23 +607,81 @@ func (rs *RedisSnowflake) generateWithRedisAssistance() (int64, error) {
 	return 0, fmt.Errorf("failed to generate unique ID after %d attempts", maxRetries)
 }
 
+// strictTimestamp returns the current millisecond for a strict-mode ID, refusing one below the last
+// issued timestamp so a restored high-water mark also guards strict mode
+// @return int64 - the current timestamp
+// @return error - ErrClockRollback if the clock is behind the last issued timestamp
+func (rs *RedisSnowflake) strictTimestamp() (int64, error) {
+	rs.node.Lock()
+	defer rs.node.Unlock()
+	timestamp := rs.currentTimeMillis()
+	if timestamp < rs.node.lastTimestamp {
+		rs.metrics.rollbacks.Add(1)
+		drift := time.Duration(rs.node.lastTimestamp-timestamp) * time.Millisecond
+		rs.emit(func(o Observer) { o.OnClockRollback(drift) })
+		return 0, ErrClockRollback
+	}
+	return timestamp, nil
+}
+
+// issuedAt raises the last issued timestamp to that of a strict-mode ID, so the high-water mark
+// reflects it
+// @param timestamp - int64 timestamp of the issued ID
+func (rs *RedisSnowflake) issuedAt(timestamp int64) {
+	rs.node.Lock()
+	if timestamp > rs.node.lastTimestamp {
+		rs.node.lastTimestamp = timestamp
+	}
+	rs.node.Unlock()
+}
+
 // Generate Generates a unique ID based on the configuration (local or with Redis assistance)
 // @return int64 - the generated unique ID
 // @return error - any error that occurred during generation
 func (rs *RedisSnowflake) Generate() (int64, error) {
+	return rs.GenerateContext(rs.ctx)
+}
+
+// GenerateContext Generates a unique ID like Generate, bounding the strict-mode Redis calls by ctx
+// @param ctx - context for the operation
+// @return int64 - the generated unique ID
+// @return error - the context error if ctx is done, or any error that occurred during generation
+func (rs *RedisSnowflake) GenerateContext(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := rs.ready(); err != nil {
+		return 0, err
+	}
+
 	// If strict mode is enabled and Redis client exists, use Redis assistance
 	if rs.strictMode && rs.redisClient != nil {
-		return rs.generateWithRedisAssistance()
+		return rs.generateWithRedisAssistance(ctx)
 	}
 
 	// Otherwise use local generation method
 	return rs.generateLocally()
 }
 
-// Cleanup Performs cleanup operations for the RedisSnowflake instance
+// Close Stops background goroutines, persists the final high-water mark and releases the slot lease
+// Generation fails with ErrClosed afterwards; further calls have no effect
+// @return error - any error that occurred while releasing the lease
+func (rs *RedisSnowflake) Close() error {
+	var err error
+	rs.closeOnce.Do(func() {
+		rs.closed.Store(true)
+		close(rs.stop)
+		rs.wg.Wait()
+		if l := rs.lease.Load(); l != nil {
+			err = l.release(rs.ctx)
+		}
+	})
+	return err
+}
+
+// Cleanup Performs cleanup operations for the RedisSnowflake instance, see Close
 func (rs *RedisSnowflake) Cleanup() {
-	// Currently no Redis data needs to be cleaned up
-	// Previous node registration functionality has been removed
+	_ = rs.Close()
 }
 
 // createInstance creates a RedisSnowflake instance with the given parameters
@@ -215,7 +691,7 @@ func (rs *RedisSnowflake) Cleanup() {
 // @return *RedisSnowflake - the created instance
 // @return error - any error that occurred during creation
 func (builder *RedisSnowflakeBuilder) createInstance(datacenterID, workerID int64, client redis.Client) (*RedisSnowflake, error) {
-	node, err := NewNode(datacenterID, workerID)
+	node, err := newNode(builder.layout, datacenterID, workerID)
 	if err != nil {
 		return nil, err
 	}
@@ -226,6 +702,18 @@ func (builder *RedisSnowflakeBuilder) createInstance(datacenterID, workerID int6
 		ctx:           context.Background(),
 		lastTimestamp: 0,
 		strictMode:    builder.strictMode,
+		stop:          make(chan struct{}),
+		clock:         builder.clock,
+
+		exhaustionPolicy: builder.exhaustionPolicy,
+		borrowLimit:      builder.borrowLimit,
+		sequenceStart:    builder.sequenceStart,
+		layout:           builder.layout,
+		keys:             builder.keys,
+		metrics:          newMetrics(),
+		watermarkStore:   builder.resolveWatermarkStore(),
+		watermarkMaxWait: builder.watermarkMaxWait,
+		rand:             newRand(builder.clock.Now().UnixNano() ^ datacenterID<<17 ^ workerID<<12),
 	}, nil
 }
 
@@ -257,19 +745,32 @@ func (builder *RedisSnowflakeBuilder) createRedisAllocatedInstance(client redis.
 	ctx := context.Background()
 
 	// Get datacenterID and workerID from Redis
-	datacenterKey := "snowflake:next_datacenter_id"
-	datacenterID, err := client.Incr(ctx, datacenterKey)
+	datacenterID, err := client.Incr(ctx, builder.keys.datacenterCounter())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get datacenter ID from Redis: %w", err)
 	}
-	datacenterID = datacenterID % MaxDatacenterIDPlusOne // Limit to 5 bits
+	datacenterID = datacenterID % (builder.layout.MaxDatacenterID() + 1) // Limit to the datacenter bits
 
-	workerKey := "snowflake:next_worker_id"
-	workerID, err := client.Incr(ctx, workerKey)
+	workerID, err := client.Incr(ctx, builder.keys.workerCounter())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get worker ID from Redis: %w", err)
 	}
-	workerID = workerID % MaxWorkerIDPlusOne // Limit to 5 bits
+	workerID = workerID % (builder.layout.MaxWorkerID() + 1) // Limit to the worker bits
 
 	return builder.createInstance(datacenterID, workerID, client)
 }
+
+// createProvidedInstance creates an instance with IDs derived from the configured WorkerIDProvider
+// @return *RedisSnowflake - the created instance
+// @return error - any error returned by the provider or by layout validation
+func (builder *RedisSnowflakeBuilder) createProvidedInstance() (*RedisSnowflake, error) {
+	datacenterID, workerID, err := builder.provider.NodeID(builder.layout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive node ID: %w", err)
+	}
+	if err := builder.layout.validateNode(datacenterID, workerID); err != nil {
+		return nil, fmt.Errorf("derived node ID %d/%d: %w", datacenterID, workerID, err)
+	}
+
+	return builder.createInstance(datacenterID, workerID, builder.client)
+}
@@ -0,0 +1,44 @@
+package snowflake
+
+import "time"
+
+// Stats Snapshot of the runtime state of a RedisSnowflake instance
+type Stats struct {
+	DatacenterID        int64            // Datacenter ID of the instance
+	WorkerID            int64            // Worker ID of the instance
+	ClockSkew           time.Duration    // Local clock minus Redis TIME at the last check, 0 when not monitored
+	ExhaustionPolicy    ExhaustionPolicy // Policy applied when a millisecond's sequence is used up
+	SequenceExhaustions uint64           // Number of times a millisecond's sequence was used up
+	ExhaustionWait      time.Duration    // Total time spent waiting for the next millisecond after exhaustion
+	Generated           uint64           // Number of IDs issued
+	ClockRollbacks      uint64           // Number of generations refused because the clock moved backwards
+	StrictRetries       uint64           // Number of strict-mode candidates already taken in Redis
+	RedisErrors         uint64           // Number of failed Redis calls made after Build
+	RedisLatency        LatencyHistogram // Latency of the Redis calls made after Build
+	LeaseTTL            time.Duration    // Remaining time of the slot lease, 0 when leasing is disabled or the lease expired
+}
+
+// Stats Returns a snapshot of the runtime state
+// @return Stats - the current statistics
+func (rs *RedisSnowflake) Stats() Stats {
+	datacenterID, workerID := rs.node.ids()
+	stats := Stats{
+		DatacenterID:        datacenterID,
+		WorkerID:            workerID,
+		ClockSkew:           time.Duration(rs.clockSkew.Load()),
+		ExhaustionPolicy:    rs.exhaustionPolicy,
+		SequenceExhaustions: rs.sequenceExhaustions.Load(),
+		ExhaustionWait:      time.Duration(rs.metrics.exhaustionWait.Load()),
+		Generated:           rs.metrics.generated.Load(),
+		ClockRollbacks:      rs.metrics.rollbacks.Load(),
+		StrictRetries:       rs.metrics.strictRetries.Load(),
+		RedisErrors:         rs.metrics.redisErrors.Load(),
+		RedisLatency:        rs.metrics.redisLatency.snapshot(),
+	}
+	if l := rs.lease.Load(); l != nil {
+		if remaining := l.expiresAt.Load() - rs.currentTimeMillis(); remaining > 0 {
+			stats.LeaseTTL = time.Duration(remaining) * time.Millisecond
+		}
+	}
+	return stats
+}
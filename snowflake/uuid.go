@@ -0,0 +1,375 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	// uuidPayloadBits is the size of rand_a and rand_b of a UUIDv7
+	uuidPayloadBits = 74
+	// ulidPayloadBits is the size of the randomness of a ULID
+	ulidPayloadBits = 80
+	// ulidLength is the number of Crockford base32 characters of a ULID
+	ulidLength = 26
+)
+
+// UUID RFC 9562 UUID, generated as version 7 by UUIDv7Generator
+type UUID [16]byte
+
+// ULID Universally Unique Lexicographically Sortable Identifier
+type ULID [16]byte
+
+// uint128 Unsigned 128-bit value
+type uint128 struct {
+	hi, lo uint64
+}
+
+// lowBits128 returns a mask of the n lowest bits
+func lowBits128(n uint) uint128 {
+	if n >= 64 {
+		return uint128{hi: 1<<(n-64) - 1, lo: ^uint64(0)}
+	}
+	return uint128{lo: 1<<n - 1}
+}
+
+// wideID Timestamp and payload of a 128-bit ID before formatting
+type wideID struct {
+	millis  int64   // Milliseconds since the Unix epoch
+	payload uint128 // Counter, node and random bits, right-aligned
+}
+
+// nodeBits returns the number of counter, datacenter and worker bits of the layout
+func (l Layout) nodeBits() uint {
+	return l.SequenceBits + l.DatacenterBits + l.WorkerBits
+}
+
+// packPayload places counter, datacenter and worker at the top of a width-bit payload and fills the rest randomly
+// The counter comes first, so IDs of one node sort in generation order within a millisecond
+// @param width - uint payload size in bits, larger than nodeBits
+// @param counter - int64 position within the millisecond
+// @param datacenterID - int64 datacenter ID
+// @param workerID - int64 worker ID
+// @param random - uint128 random bits
+// @return uint128 - the payload
+func (l Layout) packPayload(width uint, counter, datacenterID, workerID int64, random uint128) uint128 {
+	node := uint64(counter)<<(l.DatacenterBits+l.WorkerBits) | uint64(datacenterID)<<l.WorkerBits | uint64(workerID)
+	shift := width - l.nodeBits()
+	var p uint128
+	if shift >= 64 {
+		p.hi = node << (shift - 64)
+	} else {
+		p.hi = node >> (64 - shift)
+		p.lo = node << shift
+	}
+	mask := lowBits128(shift)
+	p.hi |= random.hi & mask.hi
+	p.lo |= random.lo & mask.lo
+	return p
+}
+
+// unpackPayload reverses packPayload
+// @param width - uint payload size in bits
+// @param p - uint128 the payload
+// @param millis - int64 milliseconds since the Unix epoch
+// @return Components - the decoded parts, Sequence being the counter
+func (l Layout) unpackPayload(width uint, p uint128, millis int64) Components {
+	shift := width - l.nodeBits()
+	var node uint64
+	if shift >= 64 {
+		node = p.hi >> (shift - 64)
+	} else {
+		node = p.hi<<(64-shift) | p.lo>>shift
+	}
+	return Components{
+		Timestamp:    millis,
+		Time:         time.Unix(0, millis*int64(time.Millisecond)),
+		DatacenterID: int64(node>>l.WorkerBits) & l.MaxDatacenterID(),
+		WorkerID:     int64(node) & l.MaxWorkerID(),
+		Sequence:     int64(node>>(l.DatacenterBits+l.WorkerBits)) & l.MaxSequence(),
+	}
+}
+
+// nextWideIDs issues n timestamps and payloads for 128-bit IDs
+// They share the node, sequence, clock rollback protection and exhaustion policy with Generate
+// @param n - int number of IDs
+// @param width - uint payload size in bits
+// @return []wideID - the issued IDs in generation order
+// @return error - any error that occurred during generation
+func (rs *RedisSnowflake) nextWideIDs(n int, width uint) ([]wideID, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if err := rs.ready(); err != nil {
+		return nil, err
+	}
+	random := make([]byte, 16*n)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	rs.node.Lock()
+	defer rs.node.Unlock()
+	ids := make([]wideID, n)
+	for i := range ids {
+		timestamp, err := rs.nextTimestamp()
+		if err != nil {
+			return nil, err
+		}
+		// Count from the start of the millisecond, so the counter increases whatever the sequence start
+		counter := (rs.node.sequence - rs.node.sequenceStart) & rs.layout.MaxSequence()
+		r := uint128{hi: binary.BigEndian.Uint64(random[16*i:]), lo: binary.BigEndian.Uint64(random[16*i+8:])}
+		ids[i] = wideID{
+			millis:  timestamp,
+			payload: rs.layout.packPayload(width, counter, rs.node.datacenterID, rs.node.workerID, r),
+		}
+		rs.node.lastTimestamp = timestamp
+	}
+	rs.metrics.generated.Add(uint64(n))
+	return ids, nil
+}
+
+// newUUIDv7 formats a UUIDv7: 48-bit Unix milliseconds, version, rand_a, variant and rand_b
+func newUUIDv7(w wideID) UUID {
+	randA := (w.payload.hi<<2 | w.payload.lo>>62) & 0xfff
+	var u UUID
+	binary.BigEndian.PutUint64(u[:8], uint64(w.millis)<<16|0x7000|randA)
+	binary.BigEndian.PutUint64(u[8:], 1<<63|w.payload.lo&(1<<62-1))
+	return u
+}
+
+// newULID formats a ULID: 48-bit Unix milliseconds and 80 bits of payload
+func newULID(w wideID) ULID {
+	var u ULID
+	binary.BigEndian.PutUint64(u[:8], uint64(w.millis)<<16|w.payload.hi&0xffff)
+	binary.BigEndian.PutUint64(u[8:], w.payload.lo)
+	return u
+}
+
+// DecodeUUID Splits a UUIDv7 generated with the layout into its parts
+// @param u - UUID to decode
+// @return Components - the decoded parts, Sequence being the counter within the millisecond
+// @return error - ErrInvalidID if u is not an RFC 9562 version 7 UUID
+func (l Layout) DecodeUUID(u UUID) (Components, error) {
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	if hi>>12&0xf != 7 || lo>>62 != 2 {
+		return Components{}, fmt.Errorf("%w: %s is not a version 7 UUID", ErrInvalidID, u)
+	}
+	randA := hi & 0xfff
+	payload := uint128{hi: randA >> 2, lo: randA<<62 | lo&(1<<62-1)}
+	return l.unpackPayload(uuidPayloadBits, payload, int64(hi>>16)), nil
+}
+
+// DecodeULID Splits a ULID generated with the layout into its parts
+// @param u - ULID to decode
+// @return Components - the decoded parts, Sequence being the counter within the millisecond
+func (l Layout) DecodeULID(u ULID) Components {
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	return l.unpackPayload(ulidPayloadBits, uint128{hi: hi & 0xffff, lo: lo}, int64(hi>>16))
+}
+
+// String Returns the canonical 8-4-4-4-12 hexadecimal form
+// @return string - the formatted UUID
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// ParseUUID Parses the canonical form of a UUID, in either case
+// @param s - string 36-character UUID
+// @return UUID - the parsed UUID
+// @return error - ErrInvalidID if s is malformed
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("%w: %q is not a UUID", ErrInvalidID, s)
+	}
+	compact := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(compact)); err != nil {
+		return UUID{}, fmt.Errorf("%w: %q is not a UUID", ErrInvalidID, s)
+	}
+	return u, nil
+}
+
+// MarshalText Encodes the UUID in its canonical form
+// @return []byte - the formatted UUID
+// @return error - always nil
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText Decodes the canonical form of a UUID
+// @param text - []byte formatted UUID
+// @return error - ErrInvalidID if text is malformed
+func (u *UUID) UnmarshalText(text []byte) error {
+	parsed, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// String Returns the 26-character Crockford base32 form
+// @return string - the formatted ULID
+func (u ULID) String() string {
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	var buf [ulidLength]byte
+	for i := ulidLength - 1; i >= 0; i-- {
+		buf[i] = Crockford32.alphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+// ParseULID Parses the Crockford base32 form of a ULID, ignoring case
+// @param s - string 26-character ULID
+// @return ULID - the parsed ULID
+// @return error - ErrInvalidID if s is malformed or exceeds 128 bits
+func ParseULID(s string) (ULID, error) {
+	if len(s) != ulidLength {
+		return ULID{}, fmt.Errorf("%w: %q must have %d characters", ErrInvalidID, s, ulidLength)
+	}
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		digit := Crockford32.decode[s[i]]
+		if digit == 0 || (i == 0 && digit-1 > 7) {
+			return ULID{}, fmt.Errorf("%w: invalid character %q in %q", ErrInvalidID, s[i], s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(digit-1)
+	}
+	var u ULID
+	binary.BigEndian.PutUint64(u[:8], hi)
+	binary.BigEndian.PutUint64(u[8:], lo)
+	return u, nil
+}
+
+// MarshalText Encodes the ULID in Crockford base32
+// @return []byte - the formatted ULID
+// @return error - always nil
+func (u ULID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText Decodes the Crockford base32 form of a ULID
+// @param text - []byte formatted ULID
+// @return error - ErrInvalidID if text is malformed
+func (u *ULID) UnmarshalText(text []byte) error {
+	parsed, err := ParseULID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// UUIDv7Generator Generates RFC 9562 UUIDv7 values on the node of a RedisSnowflake instance
+// rand_a and rand_b hold the counter within the millisecond, the datacenter ID, the worker ID and random bits;
+// strict mode does not apply
+type UUIDv7Generator struct {
+	rs *RedisSnowflake
+}
+
+// NewUUIDv7Generator Creates a UUIDv7 generator sharing the node, clock checks and exhaustion policy of the instance
+// @param rs - *RedisSnowflake providing the node
+// @return *UUIDv7Generator - the created generator
+func NewUUIDv7Generator(rs *RedisSnowflake) *UUIDv7Generator {
+	return &UUIDv7Generator{rs: rs}
+}
+
+// Generate Generates a UUIDv7
+// @return UUID - the generated UUID
+// @return error - any error that occurred during generation
+func (g *UUIDv7Generator) Generate() (UUID, error) {
+	ids, err := g.GenerateN(1)
+	if err != nil {
+		return UUID{}, err
+	}
+	return ids[0], nil
+}
+
+// GenerateN Generates n UUIDv7 values, increasing in generation order
+// @param n - int number of UUIDs to generate
+// @return []UUID - the generated UUIDs
+// @return error - any error that occurred during generation
+func (g *UUIDv7Generator) GenerateN(n int) ([]UUID, error) {
+	wide, err := g.rs.nextWideIDs(n, uuidPayloadBits)
+	if err != nil || wide == nil {
+		return nil, err
+	}
+	ids := make([]UUID, len(wide))
+	for i, w := range wide {
+		ids[i] = newUUIDv7(w)
+	}
+	return ids, nil
+}
+
+// Decode Splits a UUIDv7 of this generator into its parts
+// @param u - UUID to decode
+// @return Components - the decoded parts, Sequence being the counter within the millisecond
+// @return error - ErrInvalidID if u is not a version 7 UUID
+func (g *UUIDv7Generator) Decode(u UUID) (Components, error) {
+	return g.rs.layout.DecodeUUID(u)
+}
+
+// ULIDGenerator Generates ULID values on the node of a RedisSnowflake instance
+// The 80 random bits hold the counter within the millisecond, the datacenter ID, the worker ID and random bits;
+// strict mode does not apply
+type ULIDGenerator struct {
+	rs *RedisSnowflake
+}
+
+// NewULIDGenerator Creates a ULID generator sharing the node, clock checks and exhaustion policy of the instance
+// @param rs - *RedisSnowflake providing the node
+// @return *ULIDGenerator - the created generator
+func NewULIDGenerator(rs *RedisSnowflake) *ULIDGenerator {
+	return &ULIDGenerator{rs: rs}
+}
+
+// Generate Generates a ULID
+// @return ULID - the generated ULID
+// @return error - any error that occurred during generation
+func (g *ULIDGenerator) Generate() (ULID, error) {
+	ids, err := g.GenerateN(1)
+	if err != nil {
+		return ULID{}, err
+	}
+	return ids[0], nil
+}
+
+// GenerateN Generates n ULIDs, increasing in generation order
+// @param n - int number of ULIDs to generate
+// @return []ULID - the generated ULIDs
+// @return error - any error that occurred during generation
+func (g *ULIDGenerator) GenerateN(n int) ([]ULID, error) {
+	wide, err := g.rs.nextWideIDs(n, ulidPayloadBits)
+	if err != nil || wide == nil {
+		return nil, err
+	}
+	ids := make([]ULID, len(wide))
+	for i, w := range wide {
+		ids[i] = newULID(w)
+	}
+	return ids, nil
+}
+
+// Decode Splits a ULID of this generator into its parts
+// @param u - ULID to decode
+// @return Components - the decoded parts, Sequence being the counter within the millisecond
+func (g *ULIDGenerator) Decode(u ULID) Components {
+	return g.rs.layout.DecodeULID(u)
+}
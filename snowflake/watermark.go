@@ -0,0 +1,246 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+)
+
+const (
+	// DefaultWatermarkInterval is how often the high-water mark is persisted
+	DefaultWatermarkInterval = time.Second
+	// DefaultWatermarkMaxWait is how long Build waits for the clock to pass a stored high-water mark
+	DefaultWatermarkMaxWait = 5 * time.Second
+	// watermarkReserveTicks is how many intervals ahead of the clock a periodic save reserves
+	watermarkReserveTicks = 2
+)
+
+var (
+	// ErrClockBehindWatermark represents a clock that has not yet passed the persisted high-water mark
+	ErrClockBehindWatermark = errors.New("clock is behind the persisted high-water mark")
+	// ErrUnsupportedClient represents a Redis client lacking an operation a feature requires
+	ErrUnsupportedClient = errors.New("redis client does not support the required operation")
+)
+
+// WatermarkStore Interface for persisting the last-issued timestamp of a worker slot across restarts
+type WatermarkStore interface {
+	// Load loads the stored high-water mark of a worker slot
+	// @param ctx - context for the operation
+	// @param datacenterID - int64 representing the datacenter ID
+	// @param workerID - int64 representing the worker ID
+	// @return int64 - the stored timestamp in milliseconds, 0 if none was stored
+	// @return error - any error that occurred while loading
+	Load(ctx context.Context, datacenterID, workerID int64) (int64, error)
+
+	// Save stores the high-water mark of a worker slot
+	// @param ctx - context for the operation
+	// @param datacenterID - int64 representing the datacenter ID
+	// @param workerID - int64 representing the worker ID
+	// @param timestamp - int64 timestamp in milliseconds
+	// @return error - any error that occurred while saving
+	Save(ctx context.Context, datacenterID, workerID, timestamp int64) error
+}
+
+// RedisWatermarkStore Stores high-water marks in Redis under <prefix>:hwm:<datacenter>:<worker>
+type RedisWatermarkStore struct {
+	client redis.KeyValueClient
+	keys   keyspace
+}
+
+// NewRedisWatermarkStore Creates a Redis-backed WatermarkStore
+// @param client - redis.Client that also implements redis.KeyValueClient
+// @return *RedisWatermarkStore - the created store
+// @return error - ErrUnsupportedClient if the client cannot Get and Set
+func NewRedisWatermarkStore(client redis.Client) (*RedisWatermarkStore, error) {
+	kv, ok := client.(redis.KeyValueClient)
+	if !ok {
+		return nil, fmt.Errorf("%w: Get/Set", ErrUnsupportedClient)
+	}
+	return &RedisWatermarkStore{client: kv}, nil
+}
+
+// SetKeyPrefix Sets the prefix of the high-water mark keys
+// Without one, the store uses the prefix of the builder it is passed to
+// @param prefix - string key prefix (default DefaultKeyPrefix)
+// @return *RedisWatermarkStore - the store for chaining
+func (s *RedisWatermarkStore) SetKeyPrefix(prefix string) *RedisWatermarkStore {
+	s.keys = keyspace{prefix: prefix}
+	return s
+}
+
+// Load loads the stored high-water mark from Redis
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return int64 - the stored timestamp in milliseconds, 0 if none was stored
+// @return error - any error that occurred while loading
+func (s *RedisWatermarkStore) Load(ctx context.Context, datacenterID, workerID int64) (int64, error) {
+	value, err := s.client.Get(ctx, s.keys.watermark(datacenterID, workerID))
+	if errors.Is(err, redis.ErrNil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Save stores the high-water mark in Redis
+// @param ctx - context for the operation
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @param timestamp - int64 timestamp in milliseconds
+// @return error - any error that occurred while saving
+func (s *RedisWatermarkStore) Save(ctx context.Context, datacenterID, workerID, timestamp int64) error {
+	return s.client.Set(ctx, s.keys.watermark(datacenterID, workerID), timestamp, 0)
+}
+
+// FileWatermarkStore Stores high-water marks as files named snowflake-<datacenter>-<worker>.hwm in a directory
+type FileWatermarkStore struct {
+	Dir string // Directory holding the mark files, must exist
+}
+
+// Load loads the stored high-water mark from its file
+// @param ctx - context for the operation (unused)
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @return int64 - the stored timestamp in milliseconds, 0 if the file does not exist
+// @return error - any error that occurred while reading or parsing the file
+func (s FileWatermarkStore) Load(_ context.Context, datacenterID, workerID int64) (int64, error) {
+	data, err := os.ReadFile(s.path(datacenterID, workerID))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// Save atomically replaces the high-water mark file
+// @param ctx - context for the operation (unused)
+// @param datacenterID - int64 representing the datacenter ID
+// @param workerID - int64 representing the worker ID
+// @param timestamp - int64 timestamp in milliseconds
+// @return error - any error that occurred while writing the file
+func (s FileWatermarkStore) Save(_ context.Context, datacenterID, workerID, timestamp int64) error {
+	path := s.path(datacenterID, workerID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(timestamp, 10)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// path returns the file holding the high-water mark of a worker slot
+func (s FileWatermarkStore) path(datacenterID, workerID int64) string {
+	return filepath.Join(s.Dir, fmt.Sprintf("snowflake-%d-%d.hwm", datacenterID, workerID))
+}
+
+// restoreWatermark waits for the clock to pass the stored high-water mark and starts periodic persistence
+// @param rs - *RedisSnowflake the watermark belongs to
+// @return error - ErrClockBehindWatermark if the clock does not pass the mark within the allowed wait
+func (builder *RedisSnowflakeBuilder) restoreWatermark(rs *RedisSnowflake) error {
+	store := rs.watermarkStore
+	mark, err := rs.slotWatermark(rs.node.ids())
+	if err != nil {
+		return err
+	}
+	rs.node.Lock()
+	rs.node.lastTimestamp = mark
+	rs.node.Unlock()
+
+	interval := builder.watermarkInterval
+	if err := rs.saveWatermark(store, interval); err != nil {
+		return fmt.Errorf("failed to save high-water mark: %w", err)
+	}
+
+	rs.wg.Add(1)
+	go rs.runWatermark(store, interval)
+	return nil
+}
+
+// resolveWatermarkStore returns the configured store, with the builder's key prefix for a
+// RedisWatermarkStore that has none of its own
+// @return WatermarkStore - the store to use, nil without persistence
+func (builder *RedisSnowflakeBuilder) resolveWatermarkStore() WatermarkStore {
+	store := builder.watermarkStore
+	if s, ok := store.(*RedisWatermarkStore); ok && s.keys.prefix == "" {
+		// Use a copy so the caller's store keeps its own settings
+		inherited := *s
+		inherited.keys = builder.keys
+		store = &inherited
+	}
+	return store
+}
+
+// waitPastWatermark waits until the instance clock passes a stored high-water mark
+// The wait is bounded in real time, so a clock that stands still or keeps falling behind fails too
+// @param mark - int64 stored timestamp in milliseconds
+// @param maxWait - time.Duration to wait at most
+// @return error - ErrClockBehindWatermark if the clock does not pass the mark in time
+func (rs *RedisSnowflake) waitPastWatermark(mark int64, maxWait time.Duration) error {
+	now := rs.currentTimeMillis()
+	if now > mark {
+		return nil
+	}
+	behind := time.Duration(mark-now) * time.Millisecond
+	if behind >= maxWait {
+		return fmt.Errorf("%w: %v behind", ErrClockBehindWatermark, behind)
+	}
+	deadline := time.Now().Add(maxWait)
+	for rs.currentTimeMillis() <= mark {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: clock did not pass the mark within %v", ErrClockBehindWatermark, maxWait)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
+
+// runWatermark periodically persists the high-water mark until the instance is cleaned up
+// @param store - WatermarkStore to write to
+// @param interval - time.Duration between writes
+func (rs *RedisSnowflake) runWatermark(store WatermarkStore, interval time.Duration) {
+	defer rs.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rs.stop:
+			// Graceful shutdown records the exact last-issued timestamp so a restart need not wait
+			rs.node.Lock()
+			datacenterID, workerID, last := rs.node.datacenterID, rs.node.workerID, rs.node.lastTimestamp
+			rs.node.Unlock()
+			_ = store.Save(rs.ctx, datacenterID, workerID, last)
+			return
+		case <-ticker.C:
+			// A failed write is retried on the next tick
+			_ = rs.saveWatermark(store, interval)
+		}
+	}
+}
+
+// saveWatermark persists a mark reserving a few intervals ahead of the clock, so IDs issued
+// between two writes are still covered if the process dies before the next one
+// @param store - WatermarkStore to write to
+// @param interval - time.Duration between writes
+// @return error - any error returned by the store
+func (rs *RedisSnowflake) saveWatermark(store WatermarkStore, interval time.Duration) error {
+	rs.node.Lock()
+	datacenterID, workerID, mark := rs.node.datacenterID, rs.node.workerID, rs.node.lastTimestamp
+	rs.node.Unlock()
+	if now := rs.currentTimeMillis(); now > mark {
+		mark = now
+	}
+	mark += watermarkReserveTicks * interval.Milliseconds()
+	return store.Save(rs.ctx, datacenterID, workerID, mark)
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// plainClient implements only the basic redis.Client operations
+type plainClient struct {
+	redis.Client
+}
+
+// TestAdminListSlots tests that leased slots are listed with their holders in slot order
+func TestAdminListSlots(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+
+	for _, node := range [][2]int64{{3, 1}, {1, 2}, {1, 1}} {
+		sf, err := snowflake.NewBuilder().
+			SetRedisClient(client).
+			SetDatacenterID(node[0]).
+			SetWorkerID(node[1]).
+			SetLeaseTTL(time.Minute).
+			SetLeaseHolder("holder").
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to lease slot %v: %v", node, err)
+		}
+		defer sf.Cleanup()
+	}
+	// Keys that are not slots are ignored
+	_ = client.Set(ctx, "snowflake:slot:bogus", "x", 0)
+
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	slots, err := admin.ListSlots(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list slots: %v", err)
+	}
+
+	want := [][2]int64{{1, 1}, {1, 2}, {3, 1}}
+	if len(slots) != len(want) {
+		t.Fatalf("Expected %d slots, got %+v", len(want), slots)
+	}
+	for i, slot := range slots {
+		if slot.DatacenterID != want[i][0] || slot.WorkerID != want[i][1] {
+			t.Errorf("Slot %d: expected %v, got %d/%d", i, want[i], slot.DatacenterID, slot.WorkerID)
+		}
+		if slot.Holder != "holder" || slot.AcquiredAt == 0 || slot.TTL <= 0 || slot.TTL > time.Minute {
+			t.Errorf("Slot %d: unexpected metadata %+v", i, slot)
+		}
+	}
+}
+
+// TestNewAdminRequiresScan tests that a client without SCAN is rejected
+func TestNewAdminRequiresScan(t *testing.T) {
+	if _, err := snowflake.NewAdmin(&plainClient{}); !errors.Is(err, snowflake.ErrUnsupportedClient) {
+		t.Errorf("Expected ErrUnsupportedClient, got %v", err)
+	}
+}
+
+// TestAdminForceRelease tests that a stuck slot can be taken over after a forced release
+func TestAdminForceRelease(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	_ = client.Set(ctx, "snowflake:slot:1:1", `{"holder":"crashed"}`, time.Hour)
+
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	if released, err := admin.ForceRelease(ctx, 1, 1); err != nil || !released {
+		t.Fatalf("Expected the slot to be released, got %v: %v", released, err)
+	}
+	if released, err := admin.ForceRelease(ctx, 1, 1); err != nil || released {
+		t.Errorf("Expected a second release to find nothing, got %v: %v", released, err)
+	}
+
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(1).SetWorkerID(1).SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to lease the released slot: %v", err)
+	}
+	sf.Cleanup()
+}
+
+// TestAdminResetCounters tests that counters are only deleted when confirmed
+func TestAdminResetCounters(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with Redis allocation: %v", err)
+	}
+	sf.Cleanup()
+
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	counters, err := admin.ResetCounters(ctx, false)
+	if err != nil {
+		t.Fatalf("Failed to read counters: %v", err)
+	}
+	if counters["snowflake:next_datacenter_id"] != 1 || counters["snowflake:next_worker_id"] != 1 {
+		t.Errorf("Unexpected counters %v", counters)
+	}
+	if _, err := client.Get(ctx, "snowflake:next_worker_id"); err != nil {
+		t.Errorf("Unconfirmed reset should keep the counters: %v", err)
+	}
+
+	if _, err := admin.ResetCounters(ctx, true); err != nil {
+		t.Fatalf("Failed to reset counters: %v", err)
+	}
+	if counters, _ := admin.ResetCounters(ctx, false); len(counters) != 0 {
+		t.Errorf("Expected no counters after reset, got %v", counters)
+	}
+}
+
+// TestAdminResetCountersChanged tests that counters moved after being read are reported, not deleted
+func TestAdminResetCountersChanged(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with Redis allocation: %v", err)
+	}
+	sf.Cleanup()
+
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	counters, err := admin.ResetCounters(ctx, false)
+	if err != nil {
+		t.Fatalf("Failed to read counters: %v", err)
+	}
+
+	// A node allocates between reading the counters and confirming the reset
+	if _, err := client.Incr(ctx, "snowflake:next_worker_id"); err != nil {
+		t.Fatalf("Failed to allocate: %v", err)
+	}
+	err = admin.DeleteCounters(ctx, counters)
+	if !errors.Is(err, snowflake.ErrCountersChanged) || !strings.Contains(err.Error(), "snowflake:next_worker_id") {
+		t.Fatalf("Expected ErrCountersChanged naming the worker counter, got %v", err)
+	}
+	if value, err := client.Get(ctx, "snowflake:next_worker_id"); err != nil || value != "2" {
+		t.Errorf("Changed counter should be kept, got %q, %v", value, err)
+	}
+	if _, err := client.Get(ctx, "snowflake:next_datacenter_id"); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("Unchanged counter should be deleted, got %v", err)
+	}
+}
+
+// TestAdminPurgeStrictKeys tests that old keys and keys without expiration are purged across scan pages
+func TestAdminPurgeStrictKeys(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	clock := mock.NewMockClock(epochTime.Add(24 * time.Hour))
+	layout := snowflake.DefaultLayout
+	shift := layout.DatacenterBits + layout.WorkerBits + layout.SequenceBits
+
+	// 150 keys of IDs issued two hours ago, 30 recent keys and 5 recent keys without expiration
+	old := (22 * time.Hour).Milliseconds() << shift
+	recent := (24*time.Hour - time.Minute).Milliseconds() << shift
+	for i := int64(0); i < 150; i++ {
+		_, _ = client.SetNX(ctx, fmt.Sprintf("snowflake:id:%d", old+i), "1", time.Hour)
+	}
+	for i := int64(0); i < 35; i++ {
+		expiration := time.Hour
+		if i >= 30 {
+			expiration = 0
+		}
+		_, _ = client.SetNX(ctx, fmt.Sprintf("snowflake:id:%d", recent+i), "1", expiration)
+	}
+
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	admin.SetClock(clock)
+
+	if n, err := admin.PurgeStrictKeys(ctx, time.Hour, true); err != nil || n != 155 {
+		t.Errorf("Expected a dry run to find 155 keys, got %d: %v", n, err)
+	}
+	if n, err := admin.PurgeStrictKeys(ctx, time.Hour, false); err != nil || n != 155 {
+		t.Errorf("Expected 155 keys to be purged, got %d: %v", n, err)
+	}
+	if n, err := admin.PurgeStrictKeys(ctx, time.Hour, true); err != nil || n != 0 {
+		t.Errorf("Expected nothing left to purge, got %d: %v", n, err)
+	}
+	if _, err := client.Get(ctx, fmt.Sprintf("snowflake:id:%d", recent)); err != nil {
+		t.Errorf("Recent key with expiration should be kept: %v", err)
+	}
+}
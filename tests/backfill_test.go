@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// TestBackfillReservation tests that reserved slots are kept from leased nodes and required for backfill
+func TestBackfillReservation(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+
+	if _, err := admin.Backfill(ctx, 0, 0); !errors.Is(err, snowflake.ErrNotReserved) {
+		t.Errorf("Expected ErrNotReserved for a free slot, got %v", err)
+	}
+	if err := admin.ReserveBackfill(ctx, 0, 0); err != nil {
+		t.Fatalf("Failed to reserve slot: %v", err)
+	}
+	if err := admin.ReserveBackfill(ctx, 0, 0); err != nil {
+		t.Errorf("Reserving again should be a no-op, got %v", err)
+	}
+	if err := admin.ReserveBackfill(ctx, 1, 1); err != nil {
+		t.Fatalf("Failed to reserve slot: %v", err)
+	}
+
+	// Allocation skips the reserved first slot, and a fixed lease on a reserved slot is refused
+	live, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to lease a slot: %v", err)
+	}
+	defer live.Cleanup()
+	stats := live.Stats()
+	if stats.DatacenterID == 0 && stats.WorkerID == 0 {
+		t.Error("Leased node took the reserved slot")
+	}
+	_, err = snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(1).SetWorkerID(1).SetLeaseTTL(time.Minute).Build()
+	if !errors.Is(err, snowflake.ErrSlotTaken) {
+		t.Errorf("Expected ErrSlotTaken for the reserved slot, got %v", err)
+	}
+
+	// The slot of a live node can neither be reserved nor backfilled
+	if err := admin.ReserveBackfill(ctx, stats.DatacenterID, stats.WorkerID); !errors.Is(err, snowflake.ErrSlotTaken) {
+		t.Errorf("Expected ErrSlotTaken, got %v", err)
+	}
+	if _, err := admin.Backfill(ctx, stats.DatacenterID, stats.WorkerID); !errors.Is(err, snowflake.ErrNotReserved) {
+		t.Errorf("Expected ErrNotReserved for a live slot, got %v", err)
+	}
+	if err := admin.UnreserveBackfill(ctx, stats.DatacenterID, stats.WorkerID); !errors.Is(err, snowflake.ErrNotReserved) {
+		t.Errorf("Expected ErrNotReserved, got %v", err)
+	}
+
+	if _, err := admin.Backfill(ctx, 0, 0); err != nil {
+		t.Errorf("Failed to backfill on the reserved slot: %v", err)
+	}
+	if err := admin.UnreserveBackfill(ctx, 0, 0); err != nil {
+		t.Fatalf("Failed to unreserve slot: %v", err)
+	}
+	if _, err := admin.Backfill(ctx, 0, 0); !errors.Is(err, snowflake.ErrNotReserved) {
+		t.Errorf("Expected ErrNotReserved after unreserving, got %v", err)
+	}
+}
+
+// TestBackfillGenerateAt tests per-millisecond sequences for timestamps in any order, kept across a restart
+func TestBackfillGenerateAt(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	layout := snowflake.Layout{Epoch: snowflake.Epoch, DatacenterBits: 5, WorkerBits: 5, SequenceBits: 2}
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	admin.SetLayout(layout)
+	if err := admin.ReserveBackfill(ctx, 31, 30); err != nil {
+		t.Fatalf("Failed to reserve slot: %v", err)
+	}
+	gen, err := admin.Backfill(ctx, 31, 30)
+	if err != nil {
+		t.Fatalf("Failed to create backfill generator: %v", err)
+	}
+
+	first := epochTime.Add(23 * time.Hour)
+	second := epochTime.Add(24 * time.Hour)
+	times := []time.Time{second, first, second, first.Add(500 * time.Microsecond), second}
+	wantSequence := []int64{0, 0, 1, 1, 2}
+	seen := make(map[int64]bool)
+	for i, at := range times {
+		id, err := gen.GenerateAt(at)
+		if err != nil {
+			t.Fatalf("Failed to generate at %v: %v", at, err)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID %d", id)
+		}
+		seen[id] = true
+		c, _ := layout.Decode(id)
+		if c.Timestamp != at.UnixMilli() || c.DatacenterID != 31 || c.WorkerID != 30 || c.Sequence != wantSequence[i] {
+			t.Errorf("ID %d: unexpected components %+v", i, c)
+		}
+	}
+
+	// A restarted backfill continues the sequences on the same slot
+	if err := gen.Close(ctx); err != nil {
+		t.Fatalf("Failed to close generator: %v", err)
+	}
+	gen, err = admin.Backfill(ctx, 31, 30)
+	if err != nil {
+		t.Fatalf("Failed to create backfill generator after a restart: %v", err)
+	}
+	id, err := gen.GenerateAt(second)
+	if err != nil {
+		t.Fatalf("Failed to use the last sequence: %v", err)
+	}
+	if c, _ := layout.Decode(id); c.Sequence != 3 || seen[id] {
+		t.Errorf("Expected sequence 3 after the restart, got %+v", c)
+	}
+	if _, err := gen.GenerateAt(second); !errors.Is(err, snowflake.ErrBackfillExhausted) {
+		t.Errorf("Expected ErrBackfillExhausted, got %v", err)
+	}
+	if _, err := gen.GenerateAt(first); err != nil {
+		t.Errorf("Failed to generate at an earlier timestamp: %v", err)
+	}
+	if _, err := gen.GenerateAt(epochTime.Add(-time.Millisecond)); !errors.Is(err, snowflake.ErrTimestampOverflow) {
+		t.Errorf("Expected ErrTimestampOverflow, got %v", err)
+	}
+
+	// Purging deletes the sequence counters of both milliseconds
+	if n, err := admin.PurgeBackfill(ctx, 31, 30); err != nil || n != 2 {
+		t.Errorf("Expected 2 purged keys, got %d: %v", n, err)
+	}
+}
+
+// TestBackfillBeforeFirstLiveUse tests that a slot leased before its reservation is only backfilled before that lease
+func TestBackfillBeforeFirstLiveUse(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+
+	live, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to lease a slot: %v", err)
+	}
+	stats := live.Stats()
+	leasedAt := time.Now()
+	live.Cleanup()
+
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	if err := admin.ReserveBackfill(ctx, stats.DatacenterID, stats.WorkerID); err != nil {
+		t.Fatalf("Failed to reserve the released slot: %v", err)
+	}
+	gen, err := admin.Backfill(ctx, stats.DatacenterID, stats.WorkerID)
+	if err != nil {
+		t.Fatalf("Failed to create backfill generator: %v", err)
+	}
+	if _, err := gen.GenerateAt(leasedAt.Add(-time.Hour)); err != nil {
+		t.Errorf("Failed to backfill before the first live use: %v", err)
+	}
+	if _, err := gen.GenerateAt(leasedAt); !errors.Is(err, snowflake.ErrSlotUsed) {
+		t.Errorf("Expected ErrSlotUsed at the first live use, got %v", err)
+	}
+}
+
+// TestBackfillUnreserveStopsGenerator tests that a running generator notices its reservation was removed
+func TestBackfillUnreserveStopsGenerator(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	clock := mock.NewMockClock(epochTime.Add(48 * time.Hour))
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	admin.SetClock(clock)
+	if err := admin.ReserveBackfill(ctx, 31, 31); err != nil {
+		t.Fatalf("Failed to reserve slot: %v", err)
+	}
+	gen, err := admin.Backfill(ctx, 31, 31)
+	if err != nil {
+		t.Fatalf("Failed to create backfill generator: %v", err)
+	}
+	at := epochTime.Add(time.Hour)
+	if _, err := gen.GenerateAt(at); err != nil {
+		t.Fatalf("Failed to backfill: %v", err)
+	}
+
+	if err := admin.UnreserveBackfill(ctx, 31, 31); err != nil {
+		t.Fatalf("Failed to unreserve slot: %v", err)
+	}
+	clock.Advance(time.Second)
+	if _, err := gen.GenerateAt(at); !errors.Is(err, snowflake.ErrNotReserved) {
+		t.Errorf("Expected ErrNotReserved after unreserving, got %v", err)
+	}
+}
+
+// failingFirstUseClient fails every write of a first_use key
+type failingFirstUseClient struct {
+	*mock.RedisClient
+}
+
+// SetNX fails for first_use keys
+func (c *failingFirstUseClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if strings.Contains(key, ":first_use:") {
+		return false, errors.New("connection reset")
+	}
+	return c.RedisClient.SetNX(ctx, key, value, expiration)
+}
+
+// TestFirstUseRecordedOnlyForLeasedSlots tests that only a node holding the slot records its first use,
+// and that a slot whose first use cannot be recorded is not kept
+func TestFirstUseRecordedOnlyForLeasedSlots(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	if err := client.Set(ctx, "snowflake:slot:1:1", "someone-else", time.Minute); err != nil {
+		t.Fatalf("Failed to take slot: %v", err)
+	}
+	_, err := snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(1).SetWorkerID(1).SetLeaseTTL(time.Minute).Build()
+	if !errors.Is(err, snowflake.ErrSlotTaken) {
+		t.Fatalf("Expected ErrSlotTaken, got %v", err)
+	}
+	if _, err := client.Get(ctx, "snowflake:first_use:1:1"); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("A node that did not get the slot recorded its first use: %v", err)
+	}
+
+	failing := &failingFirstUseClient{RedisClient: mock.NewMockRedisClient()}
+	if _, err := snowflake.NewBuilder().SetRedisClient(failing).SetDatacenterID(2).SetWorkerID(2).SetLeaseTTL(time.Minute).Build(); err == nil {
+		t.Fatal("Expected an error when the first use cannot be recorded")
+	}
+	if _, err := failing.Get(ctx, "snowflake:slot:2:2"); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("Expected the slot to be released, got %v", err)
+	}
+}
+
+// TestPurgeBackfill tests that the first use of a reserved slot can be purged, and only while reserved
+func TestPurgeBackfill(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	live, err := snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(3).SetWorkerID(4).SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to lease a slot: %v", err)
+	}
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	if _, err := admin.PurgeBackfill(ctx, 3, 4); !errors.Is(err, snowflake.ErrNotReserved) {
+		t.Errorf("Expected ErrNotReserved for a live slot, got %v", err)
+	}
+	live.Cleanup()
+
+	if err := admin.ReserveBackfill(ctx, 3, 4); err != nil {
+		t.Fatalf("Failed to reserve slot: %v", err)
+	}
+	if n, err := admin.PurgeBackfill(ctx, 3, 4); err != nil || n != 1 {
+		t.Errorf("Expected 1 purged key, got %d: %v", n, err)
+	}
+	if _, err := client.Get(ctx, "snowflake:first_use:3:4"); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("Expected the first use to be purged, got %v", err)
+	}
+}
+
+// TestBackfillClaim tests that only one generator runs on a reserved slot at a time
+func TestBackfillClaim(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	clock := mock.NewMockClock(epochTime.Add(48 * time.Hour))
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	admin.SetClock(clock)
+	if err := admin.ReserveBackfill(ctx, 31, 31); err != nil {
+		t.Fatalf("Failed to reserve slot: %v", err)
+	}
+	gen, err := admin.Backfill(ctx, 31, 31)
+	if err != nil {
+		t.Fatalf("Failed to create backfill generator: %v", err)
+	}
+	if _, err := admin.Backfill(ctx, 31, 31); !errors.Is(err, snowflake.ErrBackfillClaimed) {
+		t.Errorf("Expected ErrBackfillClaimed for a second generator, got %v", err)
+	}
+	if err := gen.Close(ctx); err != nil {
+		t.Fatalf("Failed to close generator: %v", err)
+	}
+	next, err := admin.Backfill(ctx, 31, 31)
+	if err != nil {
+		t.Fatalf("Failed to create a generator after Close: %v", err)
+	}
+
+	// A generator whose claim was taken over stops at its next check
+	if err := client.Set(ctx, "snowflake:backfill:31:31", "someone-else", time.Minute); err != nil {
+		t.Fatalf("Failed to take over claim: %v", err)
+	}
+	clock.Advance(time.Second)
+	if _, err := next.GenerateAt(epochTime.Add(time.Hour)); !errors.Is(err, snowflake.ErrBackfillClaimed) {
+		t.Errorf("Expected ErrBackfillClaimed after the claim was taken over, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// TestIDBoundsEdges tests the bounds at both ends of the representable time range
+func TestIDBoundsEdges(t *testing.T) {
+	layout := snowflake.DefaultLayout
+	if id, err := layout.MinIDAt(layout.MinTime()); err != nil || id != 0 {
+		t.Errorf("MinIDAt(epoch): got %d, %v", id, err)
+	}
+	if id, err := layout.MaxIDAt(layout.MaxTime().Add(999 * time.Microsecond)); err != nil || id != math.MaxInt64 {
+		t.Errorf("MaxIDAt(last millisecond): got %d, %v", id, err)
+	}
+	for _, at := range []time.Time{layout.MinTime().Add(-time.Nanosecond), layout.MaxTime().Add(time.Millisecond)} {
+		if _, err := layout.MinIDAt(at); !errors.Is(err, snowflake.ErrTimestampOverflow) {
+			t.Errorf("MinIDAt(%v): expected ErrTimestampOverflow, got %v", at, err)
+		}
+		if _, err := layout.MaxIDAt(at); !errors.Is(err, snowflake.ErrTimestampOverflow) {
+			t.Errorf("MaxIDAt(%v): expected ErrTimestampOverflow, got %v", at, err)
+		}
+	}
+}
+
+// TestRangeForCustomLayout tests that the bounds honor the epoch and bit widths of the layout
+func TestRangeForCustomLayout(t *testing.T) {
+	layout := snowflake.Layout{Epoch: 1700000000000, DatacenterBits: 3, WorkerBits: 7, SequenceBits: 10}
+	start := time.UnixMilli(1700000000000 + 1000)
+	end := start.Add(24*time.Hour - time.Millisecond)
+	minID, maxID, err := layout.RangeFor(start, end)
+	if err != nil {
+		t.Fatalf("Failed to compute range: %v", err)
+	}
+	if minID != 1000<<20 {
+		t.Errorf("Expected min %d, got %d", 1000<<20, minID)
+	}
+	c, _ := layout.Decode(maxID)
+	if !c.Time.Equal(end) || c.DatacenterID != 7 || c.WorkerID != 127 || c.Sequence != 1023 {
+		t.Errorf("Unexpected max components %+v", c)
+	}
+	if next, _ := layout.MinIDAt(end.Add(time.Millisecond)); next != maxID+1 {
+		t.Errorf("Expected the next millisecond to start at %d, got %d", maxID+1, next)
+	}
+
+	if _, _, err := layout.RangeFor(end, start); !errors.Is(err, snowflake.ErrInvalidRange) {
+		t.Errorf("Expected ErrInvalidRange, got %v", err)
+	}
+	if _, _, err := layout.RangeFor(start.Add(-time.Hour), end); !errors.Is(err, snowflake.ErrTimestampOverflow) {
+		t.Errorf("Expected ErrTimestampOverflow, got %v", err)
+	}
+}
+
+// TestRangeForGeneratedIDs tests that generated IDs fall within the range of their millisecond
+func TestRangeForGeneratedIDs(t *testing.T) {
+	now := epochTime.Add(time.Hour)
+	clock := mock.NewMockClock(now)
+	sf, err := snowflake.NewBuilder().SetClock(clock).SetDatacenterID(31).SetWorkerID(31).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	ids, err := sf.GenerateN(10)
+	if err != nil {
+		t.Fatalf("Failed to generate IDs: %v", err)
+	}
+	minID, maxID, err := sf.RangeFor(now, now)
+	if err != nil {
+		t.Fatalf("Failed to compute range: %v", err)
+	}
+	for _, id := range ids {
+		if id < minID || id > maxID {
+			t.Errorf("ID %d outside [%d, %d]", id, minID, maxID)
+		}
+	}
+	if before, _ := sf.MaxIDAt(now.Add(-time.Millisecond)); before >= ids[0] {
+		t.Errorf("Previous millisecond bound %d not below %d", before, ids[0])
+	}
+	if after, _ := sf.MinIDAt(now.Add(time.Millisecond)); after <= ids[len(ids)-1] {
+		t.Errorf("Next millisecond bound %d not above %d", after, ids[len(ids)-1])
+	}
+}
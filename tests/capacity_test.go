@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// TestCapacityDefaultLayout tests the capacity report of the default layout
+func TestCapacityDefaultLayout(t *testing.T) {
+	now := epochTime.Add(time.Hour)
+	capacity := snowflake.DefaultLayout.Capacity(now)
+	exhaustsAt := epochTime.Add(time.Duration(1<<41) * time.Millisecond)
+	if !capacity.ExhaustsAt.Equal(exhaustsAt) || capacity.ExhaustsAt.Year() != 2091 {
+		t.Errorf("Expected exhaustion at %v, got %v", exhaustsAt, capacity.ExhaustsAt)
+	}
+	if capacity.Remaining != exhaustsAt.Sub(now) {
+		t.Errorf("Expected %v remaining, got %v", exhaustsAt.Sub(now), capacity.Remaining)
+	}
+	if capacity.MaxNodes != 1024 || capacity.PeakIDsPerSecond != 4096000 {
+		t.Errorf("Unexpected capacity %+v", capacity)
+	}
+
+	if remaining := snowflake.DefaultLayout.Capacity(exhaustsAt.Add(time.Hour)).Remaining; remaining != 0 {
+		t.Errorf("Expected no remaining time after exhaustion, got %v", remaining)
+	}
+	// Lifetimes beyond the largest duration are clamped
+	wide := snowflake.Layout{Epoch: snowflake.Epoch, SequenceBits: 1}
+	if remaining := wide.Capacity(now).Remaining; remaining != math.MaxInt64 {
+		t.Errorf("Expected the largest duration, got %v", remaining)
+	}
+}
+
+// TestBuildMinLifetime tests that Build refuses layouts running out within the minimum lifetime
+func TestBuildMinLifetime(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(24 * time.Hour))
+	// 22 sequence bits leave 31 timestamp bits, about 24.8 days
+	layout := snowflake.Layout{Epoch: snowflake.Epoch, DatacenterBits: 5, WorkerBits: 5, SequenceBits: 22}
+
+	_, err := snowflake.NewBuilder().SetClock(clock).SetLayout(layout).SetMinLifetime(30 * 24 * time.Hour).Build()
+	if !errors.Is(err, snowflake.ErrLifetimeTooShort) {
+		t.Errorf("Expected ErrLifetimeTooShort, got %v", err)
+	}
+
+	sf, err := snowflake.NewBuilder().SetClock(clock).SetLayout(layout).SetMinLifetime(20 * 24 * time.Hour).Build()
+	if err != nil {
+		t.Fatalf("Failed to build with enough lifetime left: %v", err)
+	}
+	defer sf.Cleanup()
+	capacity := sf.Capacity()
+	if capacity.Remaining >= 24*time.Hour*24 || capacity.Remaining < 23*24*time.Hour {
+		t.Errorf("Unexpected remaining lifetime %v", capacity.Remaining)
+	}
+
+	// A later epoch extends the lifetime
+	recent, err := snowflake.NewBuilder().SetClock(clock).SetLayout(layout).SetEpoch(clock.Now().UnixMilli()).
+		SetMinLifetime(24 * 24 * time.Hour).Build()
+	if err != nil {
+		t.Fatalf("Failed to build with a recent epoch: %v", err)
+	}
+	recent.Cleanup()
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// TestCheckedEncodingRoundTrip tests encoding, grouping and the accepted spellings
+func TestCheckedEncodingRoundTrip(t *testing.T) {
+	grouped := snowflake.NewCheckedEncoding(4)
+	r := rand.New(rand.NewSource(5))
+	ids := []snowflake.ID{0, 1, 31, 32, math.MaxInt64}
+	for i := 0; i < 1000; i++ {
+		ids = append(ids, snowflake.ID(r.Int63()))
+	}
+	for _, id := range ids {
+		plain := id.Checked32()
+		if len(plain) != 14 || !strings.HasPrefix(plain, id.Crockford32()) {
+			t.Fatalf("Unexpected checked form %q of %d", plain, id)
+		}
+		withDashes := grouped.Encode(id)
+		if withDashes != plain[:4]+"-"+plain[4:8]+"-"+plain[8:12]+"-"+plain[12:] {
+			t.Fatalf("Unexpected grouping %q of %q", withDashes, plain)
+		}
+		for _, s := range []string{plain, withDashes, strings.ToLower(withDashes)} {
+			if got, err := snowflake.Checked32.Decode(s); err != nil || got != id {
+				t.Fatalf("%q decoded to %d, expected %d: %v", s, got, id, err)
+			}
+		}
+	}
+
+	id := snowflake.ID(634740758649379215)
+	s := id.Checked32()
+	// Omitted leading zeros, misread letters and stray dashes are tolerated
+	for _, variant := range []string{
+		strings.TrimLeft(s, "0"),
+		strings.NewReplacer("0", "o", "1", "l").Replace(s),
+		"-" + s[:3] + "--" + s[3:],
+	} {
+		if got, err := snowflake.Checked32.Decode(variant); err != nil || got != id {
+			t.Errorf("%q decoded to %d: %v", variant, got, err)
+		}
+	}
+}
+
+// TestCheckedEncodingDetectsTypos tests every single-character error and adjacent transposition
+func TestCheckedEncodingDetectsTypos(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	for n := 0; n < 200; n++ {
+		s := snowflake.ID(r.Int63()).Checked32()
+		for i := 0; i < len(s); i++ {
+			for _, c := range crockfordAlphabet {
+				if byte(c) == s[i] {
+					continue
+				}
+				typo := s[:i] + string(c) + s[i+1:]
+				if _, err := snowflake.Checked32.Decode(typo); !errors.Is(err, snowflake.ErrInvalidID) {
+					t.Fatalf("Substitution %q of %q was not detected", typo, s)
+				}
+			}
+			if i+1 < len(s) && s[i] != s[i+1] {
+				swapped := s[:i] + string(s[i+1]) + string(s[i]) + s[i+2:]
+				if _, err := snowflake.Checked32.Decode(swapped); !errors.Is(err, snowflake.ErrInvalidID) {
+					t.Fatalf("Transposition %q of %q was not detected", swapped, s)
+				}
+			}
+		}
+	}
+}
+
+// TestCheckedEncodingMalformed tests input rejected before the checksum is considered
+func TestCheckedEncodingMalformed(t *testing.T) {
+	valid := snowflake.ID(42).Checked32()
+	for _, s := range []string{"", "0", "--", valid + "0", "U" + valid[1:], valid[:5] + "*" + valid[6:], "ZZZZZZZZZZZZZZ"} {
+		if _, err := snowflake.Checked32.Decode(s); !errors.Is(err, snowflake.ErrInvalidID) {
+			t.Errorf("Expected ErrInvalidID for %q, got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// recordingLogger Collects log messages for assertions
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.messages)
+}
+
+// TestClockSkewRefusesAllocation tests that a large skew refuses Redis allocation at startup
+func TestClockSkewRefusesAllocation(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+	mockRedis.SetTimeOffset(-10 * time.Second) // Local clock is 10s fast
+
+	_, err := snowflake.NewBuilder().
+		SetRedisClient(mockRedis).
+		SetClockSkewLimits(100*time.Millisecond, time.Second).
+		Build()
+	if !errors.Is(err, snowflake.ErrClockSkew) {
+		t.Fatalf("Expected ErrClockSkew, got %v", err)
+	}
+
+	// No worker slot was allocated
+	if _, err := mockRedis.Get(context.Background(), "snowflake:next_worker_id"); err == nil {
+		t.Error("Expected no allocation to happen")
+	}
+}
+
+// TestClockSkewWarning tests that a skew past the warning threshold is logged and exposed through Stats
+func TestClockSkewWarning(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+	mockRedis.SetTimeOffset(300 * time.Millisecond) // Local clock is 300ms slow
+	logger := &recordingLogger{}
+
+	sf, err := snowflake.NewBuilder().
+		SetRedisClient(mockRedis).
+		SetClockSkewLimits(100*time.Millisecond, time.Second).
+		SetLogger(logger).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with skew monitor: %v", err)
+	}
+	defer sf.Cleanup()
+
+	if logger.count() == 0 {
+		t.Error("Expected a clock skew warning to be logged")
+	}
+	if skew := sf.Stats().ClockSkew; skew > -250*time.Millisecond || skew < -350*time.Millisecond {
+		t.Errorf("Expected a skew of about -300ms, got %v", skew)
+	}
+	if _, err := sf.Generate(); err != nil {
+		t.Errorf("Generation should be allowed below the maximum: %v", err)
+	}
+}
+
+// TestClockSkewPeriodicCheck tests that generation is refused while the skew exceeds the maximum
+func TestClockSkewPeriodicCheck(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+
+	sf, err := snowflake.NewBuilder().
+		SetRedisClient(mockRedis).
+		SetDatacenterID(1).
+		SetWorkerID(1).
+		SetClockSkewLimits(100*time.Millisecond, time.Second).
+		SetClockSkewInterval(10 * time.Millisecond).
+		SetLogger(&recordingLogger{}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with skew monitor: %v", err)
+	}
+	defer sf.Cleanup()
+
+	mockRedis.SetTimeOffset(-5 * time.Second)
+	waitFor(t, func() bool {
+		_, err := sf.Generate()
+		return errors.Is(err, snowflake.ErrClockSkew)
+	})
+
+	mockRedis.SetTimeOffset(0)
+	waitFor(t, func() bool {
+		_, err := sf.Generate()
+		return err == nil
+	})
+}
+
+// TestClockSkewInvalidInterval tests that Build rejects a non-positive check interval
+func TestClockSkewInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		_, err := snowflake.NewBuilder().
+			SetRedisClient(mock.NewMockRedisClient()).
+			SetDatacenterID(1).
+			SetWorkerID(1).
+			SetClockSkewLimits(100*time.Millisecond, time.Second).
+			SetClockSkewInterval(interval).
+			Build()
+		if !errors.Is(err, snowflake.ErrInvalidInterval) {
+			t.Errorf("Interval %v: expected ErrInvalidInterval, got %v", interval, err)
+		}
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
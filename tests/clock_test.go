@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// epochTime is snowflake.Epoch as a time.Time
+var epochTime = time.Unix(0, snowflake.Epoch*int64(time.Millisecond))
+
+// TestClockRollback tests that a clock moving backwards is reported as ErrClockRollback
+func TestClockRollback(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().SetClock(clock).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with mock clock: %v", err)
+	}
+	defer sf.Cleanup()
+
+	first, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	if timestamp := first >> 22; timestamp != time.Hour.Milliseconds() {
+		t.Errorf("Expected timestamp %d, got %d", time.Hour.Milliseconds(), timestamp)
+	}
+
+	clock.Advance(-5 * time.Millisecond)
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrClockRollback) {
+		t.Errorf("Expected ErrClockRollback, got %v", err)
+	}
+
+	// Generation resumes once the clock has caught up
+	clock.Advance(6 * time.Millisecond)
+	next, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID after recovery: %v", err)
+	}
+	if next <= first {
+		t.Errorf("ID after recovery %d should be greater than %d", next, first)
+	}
+}
+
+// TestSequenceExhaustionWaitsForNextMillisecond tests the overflow wait at a millisecond boundary
+func TestSequenceExhaustionWaitsForNextMillisecond(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().SetClock(clock).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with mock clock: %v", err)
+	}
+	defer sf.Cleanup()
+
+	// Use up all 4096 sequence numbers of the millisecond
+	for i := 0; i < 4096; i++ {
+		id, err := sf.Generate()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: %v", err)
+		}
+		if seq := id & 4095; seq != int64(i) {
+			t.Fatalf("Expected sequence %d, got %d", i, seq)
+		}
+	}
+
+	done := make(chan int64)
+	go func() {
+		id, _ := sf.Generate()
+		done <- id
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Generate should wait while the sequence is exhausted")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case id := <-done:
+		if timestamp := id >> 22; timestamp != time.Hour.Milliseconds()+1 {
+			t.Errorf("Expected the next millisecond, got timestamp %d", timestamp)
+		}
+		if seq := id & 4095; seq != 0 {
+			t.Errorf("Expected sequence 0 in the next millisecond, got %d", seq)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Generate did not resume after the clock advanced")
+	}
+}
+
+// TestTimestampOverflowYear tests that IDs are refused once the 41-bit timestamp is used up
+func TestTimestampOverflowYear(t *testing.T) {
+	last := epochTime.Add(time.Duration(1<<41-1) * time.Millisecond)
+	clock := mock.NewMockClock(last)
+
+	sf, err := snowflake.NewBuilder().SetClock(clock).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with mock clock: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("The last representable millisecond should still work: %v", err)
+	}
+	if id <= 0 {
+		t.Errorf("Generated ID should be positive, got: %d", id)
+	}
+
+	clock.Advance(time.Millisecond)
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrTimestampOverflow) {
+		t.Errorf("Expected ErrTimestampOverflow, got %v", err)
+	}
+}
+
+// TestStrictModeRetriesWithinMillisecond tests strict-mode retries against a frozen clock
+func TestStrictModeRetriesWithinMillisecond(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().
+		SetRedisClient(mock.NewMockRedisClient()).
+		SetDatacenterID(1).
+		SetWorkerID(1).
+		SetStrictMode(true).
+		SetClock(clock).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize in strict mode: %v", err)
+	}
+	defer sf.Cleanup()
+
+	// Each call may retry up to 10 times, so a frozen millisecond yields exactly 10 unique IDs
+	seen := make(map[int64]bool)
+	for i := 0; i < 10; i++ {
+		id, err := sf.Generate()
+		if err != nil {
+			t.Fatalf("Failed to generate ID %d in strict mode: %v", i, err)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID in strict mode: %d", id)
+		}
+		seen[id] = true
+	}
+	if _, err := sf.Generate(); err == nil {
+		t.Error("Expected strict mode to give up once all retries collide")
+	}
+
+	clock.Advance(time.Millisecond)
+	if _, err := sf.Generate(); err != nil {
+		t.Errorf("Strict mode should recover in the next millisecond: %v", err)
+	}
+}
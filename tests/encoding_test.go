@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+var encodings = map[string]*snowflake.Encoding{
+	"base62":      snowflake.Base62,
+	"base58":      snowflake.Base58,
+	"crockford32": snowflake.Crockford32,
+	"hex":         snowflake.Hex,
+}
+
+// TestEncodingRoundTrip tests boundary and random IDs through every encoding
+func TestEncodingRoundTrip(t *testing.T) {
+	widths := map[string]int{"base62": 11, "base58": 11, "crockford32": 13, "hex": 16}
+	r := rand.New(rand.NewSource(1))
+	ids := []snowflake.ID{0, 1, 57, 58, 61, 62, math.MaxInt64 - 1, math.MaxInt64}
+	for i := 0; i < 1000; i++ {
+		ids = append(ids, snowflake.ID(r.Int63()))
+	}
+
+	for name, enc := range encodings {
+		if enc.Width() != widths[name] {
+			t.Errorf("%s: expected width %d, got %d", name, widths[name], enc.Width())
+		}
+		for _, id := range ids {
+			s := enc.Encode(id)
+			if len(s) != enc.Width() {
+				t.Fatalf("%s: %d encoded to %q of the wrong width", name, id, s)
+			}
+			got, err := enc.Decode(s)
+			if err != nil || got != id {
+				t.Fatalf("%s: %d round-tripped through %q to %d: %v", name, id, s, got, err)
+			}
+		}
+	}
+
+	id := snowflake.ID(634740758649379215)
+	for name, s := range map[string]string{
+		"base62": id.Base62(), "base58": id.Base58(), "crockford32": id.Crockford32(), "hex": id.Hex(),
+	} {
+		if s != encodings[name].Encode(id) {
+			t.Errorf("%s: method and encoding disagree: %q", name, s)
+		}
+	}
+	if id.Hex() != "08cf0d5b63c2198f" {
+		t.Errorf("Unexpected hex form %q", id.Hex())
+	}
+}
+
+// TestEncodingOrder tests that encoded IDs sort lexicographically in numeric order
+func TestEncodingOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	ids := make([]snowflake.ID, 1000)
+	for i := range ids {
+		// Mix small and large values so the padding is exercised
+		ids[i] = snowflake.ID(r.Int63() >> uint(r.Intn(63)))
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for name, enc := range encodings {
+		encoded := make([]string, len(ids))
+		for i, id := range ids {
+			encoded[i] = enc.Encode(id)
+		}
+		if !sort.StringsAreSorted(encoded) {
+			t.Errorf("%s: encoded IDs are not in numeric order", name)
+		}
+	}
+}
+
+// TestEncodingLenientDecoding tests the accepted alternative spellings
+func TestEncodingLenientDecoding(t *testing.T) {
+	for _, tc := range []struct {
+		enc  *snowflake.Encoding
+		in   string
+		want snowflake.ID
+	}{
+		{snowflake.Base62, "z", 61},
+		{snowflake.Base62, "10", 62},
+		{snowflake.Base58, "21", 58},
+		{snowflake.Crockford32, "0000000000010", 32},
+		{snowflake.Crockford32, "10", 32},
+		{snowflake.Crockford32, "1o", 32},
+		{snowflake.Crockford32, "iO", 32},
+		{snowflake.Crockford32, "L0", 32},
+		{snowflake.Crockford32, "zz", 1023},
+		{snowflake.Crockford32, "000-000-000-0010", 32},
+		{snowflake.Hex, "FF", 255},
+		{snowflake.Hex, "7fffffffffffffff", math.MaxInt64},
+	} {
+		got, err := tc.enc.Decode(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("Decode %q: expected %d, got %d: %v", tc.in, tc.want, got, err)
+		}
+	}
+}
+
+// TestEncodingMalformed tests that invalid input is rejected with ErrInvalidID
+func TestEncodingMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		enc *snowflake.Encoding
+		in  string
+	}{
+		{snowflake.Base62, ""},
+		{snowflake.Base62, "abc-def"},
+		{snowflake.Base62, "000000000000"},
+		{snowflake.Base62, "zzzzzzzzzzz"},
+		{snowflake.Base62, "AzL8n0Y58m8"},
+		{snowflake.Base58, "0"},
+		{snowflake.Base58, "O"},
+		{snowflake.Base58, "I"},
+		{snowflake.Base58, "l"},
+		{snowflake.Crockford32, "U"},
+		{snowflake.Crockford32, "---"},
+		{snowflake.Crockford32, "8000000000000"},
+		{snowflake.Crockford32, "00000000000000"},
+		{snowflake.Hex, "0x10"},
+		{snowflake.Hex, "g"},
+		{snowflake.Hex, "8000000000000000"},
+		{snowflake.Hex, " 1"},
+	} {
+		if got, err := tc.enc.Decode(tc.in); !errors.Is(err, snowflake.ErrInvalidID) {
+			t.Errorf("Decode %q: expected ErrInvalidID, got %d, %v", tc.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// exhaustMillisecond generates the 4096 IDs available in the current millisecond
+func exhaustMillisecond(t *testing.T, sf *snowflake.RedisSnowflake) {
+	t.Helper()
+	for i := 0; i < 4096; i++ {
+		if _, err := sf.Generate(); err != nil {
+			t.Fatalf("Failed to generate ID %d: %v", i, err)
+		}
+	}
+}
+
+// TestExhaustionError tests that ExhaustionError returns ErrOverFlow until the next millisecond
+func TestExhaustionError(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().
+		SetClock(clock).
+		SetExhaustionPolicy(snowflake.ExhaustionError).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	exhaustMillisecond(t, sf)
+	for i := 0; i < 2; i++ {
+		if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrOverFlow) {
+			t.Fatalf("Expected ErrOverFlow, got %v", err)
+		}
+	}
+
+	clock.Advance(time.Millisecond)
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID in the next millisecond: %v", err)
+	}
+	if seq := id & 4095; seq != 0 {
+		t.Errorf("Expected sequence 0, got %d", seq)
+	}
+
+	stats := sf.Stats()
+	if stats.ExhaustionPolicy != snowflake.ExhaustionError || stats.SequenceExhaustions != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestExhaustionSleep tests that ExhaustionSleep waits for the clock to reach the next millisecond
+func TestExhaustionSleep(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().
+		SetClock(clock).
+		SetExhaustionPolicy(snowflake.ExhaustionSleep).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	exhaustMillisecond(t, sf)
+	done := make(chan int64)
+	go func() {
+		id, _ := sf.Generate()
+		done <- id
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Generate should sleep while the sequence is exhausted")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case id := <-done:
+		if timestamp := id >> 22; timestamp != time.Hour.Milliseconds()+1 {
+			t.Errorf("Expected the next millisecond, got timestamp %d", timestamp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Generate did not resume after the clock advanced")
+	}
+}
+
+// TestExhaustionBorrow tests borrowing future milliseconds up to the limit
+func TestExhaustionBorrow(t *testing.T) {
+	start := time.Hour.Milliseconds()
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().
+		SetClock(clock).
+		SetExhaustionPolicy(snowflake.ExhaustionBorrow).
+		SetBorrowLimit(2 * time.Millisecond).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	// The frozen millisecond plus two borrowed ones are available without waiting
+	var last int64
+	for i := 0; i < 3*4096; i++ {
+		id, err := sf.Generate()
+		if err != nil {
+			t.Fatalf("Failed to generate ID %d: %v", i, err)
+		}
+		if id <= last {
+			t.Fatalf("IDs should be increasing, got %d after %d", id, last)
+		}
+		last = id
+	}
+	if timestamp := last >> 22; timestamp != start+2 {
+		t.Errorf("Expected to borrow up to timestamp %d, got %d", start+2, timestamp)
+	}
+
+	// Borrowing further waits for the clock
+	done := make(chan int64)
+	go func() {
+		id, _ := sf.Generate()
+		done <- id
+	}()
+	select {
+	case <-done:
+		t.Fatal("Generate should wait once the borrow limit is reached")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case id := <-done:
+		if timestamp := id >> 22; timestamp != start+3 {
+			t.Errorf("Expected timestamp %d, got %d", start+3, timestamp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Generate did not resume after the clock advanced")
+	}
+
+	stats := sf.Stats()
+	if stats.ExhaustionPolicy != snowflake.ExhaustionBorrow || stats.SequenceExhaustions != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestExhaustionPolicyString tests the policy names reported in stats
+func TestExhaustionPolicyString(t *testing.T) {
+	names := map[snowflake.ExhaustionPolicy]string{
+		snowflake.ExhaustionSpin:   "spin",
+		snowflake.ExhaustionSleep:  "sleep",
+		snowflake.ExhaustionError:  "error",
+		snowflake.ExhaustionBorrow: "borrow",
+	}
+	for policy, name := range names {
+		if policy.String() != name {
+			t.Errorf("Expected %q, got %q", name, policy.String())
+		}
+	}
+
+	sf, err := snowflake.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+	if policy := sf.Stats().ExhaustionPolicy; policy != snowflake.ExhaustionSpin {
+		t.Errorf("Expected default policy spin, got %v", policy)
+	}
+}
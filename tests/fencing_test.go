@@ -0,0 +1,334 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/redis"
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// TestFencingStopsWhileLeaseExpired tests that generation stops once the lease runs out and resumes after renewal
+func TestFencingStopsWhileLeaseExpired(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(60 * time.Millisecond).
+		SetFencing(true).SetLogger(&recordingLogger{}).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with fencing: %v", err)
+	}
+	defer sf.Cleanup()
+	if _, err := sf.Generate(); err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+
+	client.SetFailure(errors.New("connection refused"))
+	waitFor(t, func() bool {
+		_, err := sf.Generate()
+		return errors.Is(err, snowflake.ErrLeaseLost)
+	})
+	if _, err := sf.GenerateN(3); !errors.Is(err, snowflake.ErrLeaseLost) {
+		t.Errorf("Expected GenerateN to fail with ErrLeaseLost, got %v", err)
+	}
+
+	client.SetFailure(nil)
+	waitFor(t, func() bool {
+		_, err := sf.Generate()
+		return err == nil
+	})
+}
+
+// TestFencingMovesToAnotherSlot tests that a fenced instance whose slot was taken leases a different one
+func TestFencingMovesToAnotherSlot(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(60 * time.Millisecond).
+		SetFencing(true).SetLogger(&recordingLogger{}).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with fencing: %v", err)
+	}
+	defer sf.Cleanup()
+
+	before := sf.Stats()
+	slotKey := fmt.Sprintf("snowflake:slot:%d:%d", before.DatacenterID, before.WorkerID)
+	if err := client.Set(context.Background(), slotKey, "someone-else", time.Minute); err != nil {
+		t.Fatalf("Failed to take over slot key: %v", err)
+	}
+	waitFor(t, func() bool {
+		after := sf.Stats()
+		return after.DatacenterID != before.DatacenterID || after.WorkerID != before.WorkerID
+	})
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID after moving: %v", err)
+	}
+	after := sf.Stats()
+	decoded, err := snowflake.Decode(id)
+	if err != nil {
+		t.Fatalf("Failed to decode ID: %v", err)
+	}
+	if decoded.DatacenterID != after.DatacenterID || decoded.WorkerID != after.WorkerID {
+		t.Errorf("ID %d was issued for %d/%d, expected the new slot %d/%d",
+			id, decoded.DatacenterID, decoded.WorkerID, after.DatacenterID, after.WorkerID)
+	}
+	if err := sf.Health(context.Background()); err != nil {
+		t.Errorf("Expected a healthy instance on the new slot, got %v", err)
+	}
+	if value, _ := client.Get(context.Background(), slotKey); value != "someone-else" {
+		t.Errorf("The taken slot should keep its new holder, got %q", value)
+	}
+}
+
+// TestNoFencingKeepsGenerating tests that without fencing an expired lease does not stop generation
+func TestNoFencingKeepsGenerating(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(30 * time.Millisecond).
+		SetLogger(&recordingLogger{}).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+	defer sf.Cleanup()
+
+	client.SetFailure(errors.New("connection refused"))
+	time.Sleep(60 * time.Millisecond)
+	if _, err := sf.Generate(); err != nil {
+		t.Errorf("Expected generation to continue without fencing, got %v", err)
+	}
+}
+
+// TestFencingVanishedKey tests that a fenced instance whose slot key vanished leases a slot again instead of reclaiming it
+func TestFencingVanishedKey(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(60 * time.Millisecond).
+		SetFencing(true).SetLogger(&recordingLogger{}).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with fencing: %v", err)
+	}
+	defer sf.Cleanup()
+
+	before := sf.Stats()
+	slotKey := fmt.Sprintf("snowflake:slot:%d:%d", before.DatacenterID, before.WorkerID)
+	if _, err := client.Del(context.Background(), slotKey); err != nil {
+		t.Fatalf("Failed to delete slot key: %v", err)
+	}
+	waitFor(t, func() bool {
+		after := sf.Stats()
+		return after.DatacenterID != before.DatacenterID || after.WorkerID != before.WorkerID
+	})
+	if _, err := sf.Generate(); err != nil {
+		t.Fatalf("Failed to generate ID after moving: %v", err)
+	}
+	if _, err := client.Get(context.Background(), slotKey); err == nil {
+		t.Error("The vanished slot key should not be reclaimed")
+	}
+}
+
+// TestFencingWaitsForWatermarkOfNewSlot tests that a fenced instance stays fenced until the clock passes
+// the high-water mark of the slot it moves to
+func TestFencingWaitsForWatermarkOfNewSlot(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	store, err := snowflake.NewRedisWatermarkStore(client)
+	if err != nil {
+		t.Fatalf("Failed to create watermark store: %v", err)
+	}
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(60 * time.Millisecond).
+		SetFencing(true).SetWatermarkStore(store).SetLogger(&recordingLogger{}).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with fencing: %v", err)
+	}
+	defer sf.Cleanup()
+
+	// Every other slot was used by a node whose clock ran 300ms ahead
+	before := sf.Stats()
+	mark := time.Now().Add(300 * time.Millisecond).UnixMilli()
+	layout := snowflake.DefaultLayout
+	for dc := int64(0); dc <= layout.MaxDatacenterID(); dc++ {
+		for w := int64(0); w <= layout.MaxWorkerID(); w++ {
+			if dc != before.DatacenterID || w != before.WorkerID {
+				if err := store.Save(ctx, dc, w, mark); err != nil {
+					t.Fatalf("Failed to save watermark: %v", err)
+				}
+			}
+		}
+	}
+
+	slotKey := fmt.Sprintf("snowflake:slot:%d:%d", before.DatacenterID, before.WorkerID)
+	if err := client.Set(ctx, slotKey, "someone-else", time.Minute); err != nil {
+		t.Fatalf("Failed to take over slot key: %v", err)
+	}
+	waitFor(t, func() bool {
+		_, err := sf.Generate()
+		return errors.Is(err, snowflake.ErrLeaseLost)
+	})
+	waitFor(t, func() bool {
+		_, err := sf.Generate()
+		return err == nil
+	})
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID after moving: %v", err)
+	}
+	decoded, err := snowflake.Decode(id)
+	if err != nil {
+		t.Fatalf("Failed to decode ID: %v", err)
+	}
+	if decoded.Timestamp <= mark {
+		t.Errorf("ID %d was issued at %d, not past the watermark %d of the new slot", id, decoded.Timestamp, mark)
+	}
+}
+
+// takeoverClient hands a slot key to another holder once armed, right after the key is read or right
+// before it is changed, so a renewal or release that reads and writes separately acts on the new holder
+type takeoverClient struct {
+	*mock.RedisClient
+	key   string
+	armed atomic.Bool
+}
+
+// takeover gives the key to another holder without a timeout, once per arming
+func (c *takeoverClient) takeover(key string) {
+	if key == c.key && c.armed.CompareAndSwap(true, false) {
+		_ = c.RedisClient.Set(context.Background(), key, "someone-else", 0)
+	}
+}
+
+// Get reads the key, then hands it over
+func (c *takeoverClient) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.RedisClient.Get(ctx, key)
+	c.takeover(key)
+	return value, err
+}
+
+// Expire hands the key over, then sets its timeout
+func (c *takeoverClient) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	c.takeover(key)
+	return c.RedisClient.Expire(ctx, key, expiration)
+}
+
+// Del hands the keys over, then deletes them
+func (c *takeoverClient) Del(ctx context.Context, keys ...string) (int64, error) {
+	for _, key := range keys {
+		c.takeover(key)
+	}
+	return c.RedisClient.Del(ctx, keys...)
+}
+
+// CompareAndExpire hands the key over, then compares and sets its timeout
+func (c *takeoverClient) CompareAndExpire(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	c.takeover(key)
+	return c.RedisClient.CompareAndExpire(ctx, key, value, expiration)
+}
+
+// CompareAndDelete hands the key over, then compares and deletes it
+func (c *takeoverClient) CompareAndDelete(ctx context.Context, key string, value string) (bool, error) {
+	c.takeover(key)
+	return c.RedisClient.CompareAndDelete(ctx, key, value)
+}
+
+// TestLeaseTakenOverBetweenReadAndWrite tests that neither renewal nor release touches a slot key
+// that changed holder while the lease was being renewed or released
+func TestLeaseTakenOverBetweenReadAndWrite(t *testing.T) {
+	ctx := context.Background()
+	client := &takeoverClient{RedisClient: mock.NewMockRedisClient(), key: "snowflake:slot:1:1"}
+	observer := newRecordingObserver()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(1).SetWorkerID(1).
+		SetLeaseTTL(30 * time.Millisecond).SetObserver(observer).SetLogger(&recordingLogger{}).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+
+	observer.expect(t, "allocated 1/1")
+	client.armed.Store(true)
+	observer.expect(t, "renew failed")
+	observer.expect(t, "lease lost")
+	if ttl, err := client.TTL(ctx, client.key); err != nil || ttl != -1 {
+		t.Errorf("Renewal extended the new holder's key, got TTL %v: %v", ttl, err)
+	}
+
+	sf.Cleanup()
+	if value, err := client.Get(ctx, client.key); err != nil || value != "someone-else" {
+		t.Errorf("Release deleted the new holder's key, got %q: %v", value, err)
+	}
+
+	client.key = "snowflake:slot:2:2"
+	sf, err = snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(2).SetWorkerID(2).
+		SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+	client.armed.Store(true)
+	sf.Cleanup()
+	if value, err := client.Get(ctx, client.key); err != nil || value != "someone-else" {
+		t.Errorf("Release deleted the new holder's key, got %q: %v", value, err)
+	}
+}
+
+// TestFencingRequiresLease tests that fencing is rejected on an instance that would hold no lease
+func TestFencingRequiresLease(t *testing.T) {
+	builders := map[string]*snowflake.RedisSnowflakeBuilder{
+		"no client": snowflake.NewBuilder().SetLeaseTTL(time.Minute).SetFencing(true),
+		"no TTL":    snowflake.NewBuilder().SetRedisClient(mock.NewMockRedisClient()).SetFencing(true),
+	}
+	for name, builder := range builders {
+		if _, err := builder.Build(); !errors.Is(err, snowflake.ErrFencingWithoutLease) {
+			t.Errorf("%s: expected ErrFencingWithoutLease, got %v", name, err)
+		}
+	}
+}
+
+// lostReplyClient renews leases but reports every renewal as failed, as if the reply was lost
+type lostReplyClient struct {
+	*mock.RedisClient
+}
+
+// CompareAndExpire forwards the renewal, then fails
+func (c *lostReplyClient) CompareAndExpire(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	_, _ = c.RedisClient.CompareAndExpire(ctx, key, value, expiration)
+	return false, errors.New("i/o timeout")
+}
+
+// TestFencingReleasesOldSlot tests that moving to another slot writes a fresh lease value and frees
+// the old slot key that still names this process
+func TestFencingReleasesOldSlot(t *testing.T) {
+	ctx := context.Background()
+	client := &lostReplyClient{RedisClient: mock.NewMockRedisClient()}
+	clock := mock.NewMockClock(time.Now())
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetClock(clock).SetLeaseTTL(60 * time.Millisecond).
+		SetLeaseHolder("test-holder").SetFencing(true).SetLogger(&recordingLogger{}).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with fencing: %v", err)
+	}
+	defer sf.Cleanup()
+
+	before := sf.Stats()
+	oldKey := fmt.Sprintf("snowflake:slot:%d:%d", before.DatacenterID, before.WorkerID)
+	// The lease runs out on the local clock while Redis still holds the key
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool {
+		after := sf.Stats()
+		return after.DatacenterID != before.DatacenterID || after.WorkerID != before.WorkerID
+	})
+
+	if _, err := client.Get(ctx, oldKey); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("Expected the old slot key to be released, got %v", err)
+	}
+	after := sf.Stats()
+	value, err := client.Get(ctx, fmt.Sprintf("snowflake:slot:%d:%d", after.DatacenterID, after.WorkerID))
+	if err != nil {
+		t.Fatalf("New slot key not written: %v", err)
+	}
+	var info snowflake.LeaseInfo
+	if err := json.Unmarshal([]byte(value), &info); err != nil {
+		t.Fatalf("Unexpected lease value %q: %v", value, err)
+	}
+	if info.Holder != "test-holder" || info.AcquiredAt != clock.Now().UnixMilli() {
+		t.Errorf("Expected a fresh lease value for test-holder at %d, got %+v", clock.Now().UnixMilli(), info)
+	}
+}
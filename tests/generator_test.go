@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// collectIDs generates through the interface the way a service would
+func collectIDs(t *testing.T, gen snowflake.IDGenerator) []int64 {
+	t.Helper()
+	first, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	second, err := gen.GenerateContext(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to generate ID with context: %v", err)
+	}
+	batch, err := gen.GenerateN(2)
+	if err != nil {
+		t.Fatalf("Failed to generate batch: %v", err)
+	}
+	return append([]int64{first, second}, batch...)
+}
+
+// TestIDGeneratorImplementations tests that every generator can be used through IDGenerator
+func TestIDGeneratorImplementations(t *testing.T) {
+	local, err := snowflake.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize local instance: %v", err)
+	}
+	strict, err := snowflake.NewBuilder().SetRedisClient(mock.NewMockRedisClient()).
+		SetDatacenterID(1).SetWorkerID(1).SetStrictMode(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize strict instance: %v", err)
+	}
+
+	for name, gen := range map[string]snowflake.IDGenerator{"local": local, "strict": strict} {
+		seen := make(map[int64]bool)
+		for _, id := range collectIDs(t, gen) {
+			if seen[id] {
+				t.Errorf("%s: duplicate ID %d", name, id)
+			}
+			seen[id] = true
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := gen.GenerateContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", name, err)
+		}
+		if err := gen.Close(); err != nil {
+			t.Errorf("%s: unexpected close error: %v", name, err)
+		}
+		if _, err := gen.Generate(); !errors.Is(err, snowflake.ErrClosed) {
+			t.Errorf("%s: expected ErrClosed, got %v", name, err)
+		}
+	}
+}
+
+// TestSequentialGenerator tests that the fake returns consecutive IDs from its start
+func TestSequentialGenerator(t *testing.T) {
+	gen := snowflake.NewSequentialGenerator(100)
+	ids := collectIDs(t, gen)
+	for i, id := range ids {
+		if id != int64(100+i) {
+			t.Fatalf("Expected consecutive IDs from 100, got %v", ids)
+		}
+	}
+	if err := gen.Close(); err != nil {
+		t.Errorf("Unexpected close error: %v", err)
+	}
+	if _, err := gen.GenerateN(1); !errors.Is(err, snowflake.ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// TestHealthReportsTypedErrors tests the healthy, Redis failure, slot taken and closed states
+func TestHealthReportsTypedErrors(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+	defer sf.Cleanup()
+
+	if err := sf.Health(ctx); err != nil {
+		t.Fatalf("Expected a healthy instance, got %v", err)
+	}
+	stats := sf.Stats()
+	slotKey := fmt.Sprintf("snowflake:slot:%d:%d", stats.DatacenterID, stats.WorkerID)
+
+	client.SetFailure(errors.New("connection refused"))
+	if err := sf.Health(ctx); !errors.Is(err, snowflake.ErrRedisUnavailable) {
+		t.Errorf("Expected ErrRedisUnavailable, got %v", err)
+	}
+	client.SetFailure(nil)
+
+	if err := client.Set(ctx, slotKey, "someone-else", 0); err != nil {
+		t.Fatalf("Failed to overwrite slot key: %v", err)
+	}
+	if err := sf.Health(ctx); !errors.Is(err, snowflake.ErrSlotTaken) {
+		t.Errorf("Expected ErrSlotTaken, got %v", err)
+	}
+	if _, err := client.Del(ctx, slotKey); err != nil {
+		t.Fatalf("Failed to delete slot key: %v", err)
+	}
+	if err := sf.Health(ctx); !errors.Is(err, snowflake.ErrLeaseExpired) {
+		t.Errorf("Expected ErrLeaseExpired, got %v", err)
+	}
+
+	if err := sf.Close(); err != nil {
+		t.Errorf("Unexpected close error: %v", err)
+	}
+	if err := sf.Health(ctx); !errors.Is(err, snowflake.ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrClosed) {
+		t.Errorf("Expected Generate to fail with ErrClosed, got %v", err)
+	}
+	if err := sf.Close(); err != nil {
+		t.Errorf("Second close should be a no-op, got %v", err)
+	}
+}
+
+// TestHealthLeaseExpiring tests that a lease past two thirds of its TTL is reported
+func TestHealthLeaseExpiring(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+	sf, err := snowflake.NewBuilder().SetRedisClient(mock.NewMockRedisClient()).
+		SetLeaseTTL(time.Minute).SetClock(clock).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+	defer sf.Cleanup()
+
+	clock.Advance(45 * time.Second)
+	if err := sf.Health(context.Background()); !errors.Is(err, snowflake.ErrLeaseExpiring) {
+		t.Errorf("Expected ErrLeaseExpiring, got %v", err)
+	}
+	clock.Advance(time.Minute)
+	if err := sf.Health(context.Background()); !errors.Is(err, snowflake.ErrLeaseExpired) {
+		t.Errorf("Expected ErrLeaseExpired, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// TestIDJSON tests that IDs marshal as strings and unmarshal from both forms
+func TestIDJSON(t *testing.T) {
+	type order struct {
+		ID snowflake.ID `json:"id"`
+	}
+	id := snowflake.ID(1<<62 + 1)
+	data, err := json.Marshal(order{ID: id})
+	if err != nil {
+		t.Fatalf("Failed to marshal ID: %v", err)
+	}
+	if string(data) != `{"id":"4611686018427387905"}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	for _, input := range []string{`{"id":"4611686018427387905"}`, `{"id":4611686018427387905}`} {
+		var got order
+		if err := json.Unmarshal([]byte(input), &got); err != nil || got.ID != id {
+			t.Errorf("Unmarshal %s: got %d, %v", input, got.ID, err)
+		}
+	}
+	for _, input := range []string{`{"id":"abc"}`, `{"id":-1}`, `{"id":1.5}`} {
+		var got order
+		if err := json.Unmarshal([]byte(input), &got); !errors.Is(err, snowflake.ErrInvalidID) {
+			t.Errorf("Unmarshal %s: expected ErrInvalidID, got %v", input, err)
+		}
+	}
+
+	// Map keys use the text form
+	data, err = json.Marshal(map[snowflake.ID]int{7: 1})
+	if err != nil || string(data) != `{"7":1}` {
+		t.Errorf("Unexpected map JSON %s: %v", data, err)
+	}
+}
+
+// TestIDSQL tests scanning from the column types drivers return and storing as an integer
+func TestIDSQL(t *testing.T) {
+	for _, src := range []interface{}{int64(42), []byte("42"), "42"} {
+		var id snowflake.ID
+		if err := id.Scan(src); err != nil || id != 42 {
+			t.Errorf("Scan %T: got %d, %v", src, id, err)
+		}
+	}
+	for _, src := range []interface{}{nil, int64(-1), 4.2} {
+		var id snowflake.ID
+		if err := id.Scan(src); !errors.Is(err, snowflake.ErrInvalidID) {
+			t.Errorf("Scan %v: expected ErrInvalidID, got %v", src, err)
+		}
+	}
+	if v, err := snowflake.ID(42).Value(); err != nil || v != int64(42) {
+		t.Errorf("Unexpected value %v: %v", v, err)
+	}
+}
+
+// TestGenerateID tests the typed generation variants and decoding helpers
+func TestGenerateID(t *testing.T) {
+	sf, err := snowflake.NewBuilder().SetDatacenterID(3).SetWorkerID(4).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.GenerateID()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	parsed, err := snowflake.ParseID(id.String())
+	if err != nil || parsed != id {
+		t.Errorf("Round trip of %s gave %d, %v", id, parsed, err)
+	}
+	c, err := id.Decode()
+	if err != nil || c.DatacenterID != 3 || c.WorkerID != 4 || !c.Time.Equal(id.Time()) {
+		t.Errorf("Unexpected components %+v: %v", c, err)
+	}
+
+	ids, err := sf.GenerateIDs(3)
+	if err != nil || len(ids) != 3 || ids[0] <= id || ids[2] <= ids[1] {
+		t.Errorf("Unexpected batch %v: %v", ids, err)
+	}
+}
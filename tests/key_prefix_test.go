@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// TestKeyPrefixSeparatesDomains tests that two prefixes allocate from independent counters
+func TestKeyPrefixSeparatesDomains(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+
+	for _, prefix := range []string{"{orders}", "{users}"} {
+		sf, err := snowflake.NewBuilder().SetRedisClient(client).SetKeyPrefix(prefix).Build()
+		if err != nil {
+			t.Fatalf("Failed to initialize with prefix %s: %v", prefix, err)
+		}
+		defer sf.Cleanup()
+		if stats := sf.Stats(); stats.DatacenterID != 1 || stats.WorkerID != 1 {
+			t.Errorf("Prefix %s: expected the first node 1/1, got %d/%d", prefix, stats.DatacenterID, stats.WorkerID)
+		}
+		if value, err := client.Get(ctx, prefix+":next_worker_id"); err != nil || value != "1" {
+			t.Errorf("Prefix %s: expected its own worker counter at 1, got %q: %v", prefix, value, err)
+		}
+	}
+	if _, err := client.Get(ctx, "snowflake:next_worker_id"); err == nil {
+		t.Error("Default counter should not be touched when a prefix is set")
+	}
+}
+
+// TestKeyPrefixCoversEveryKey tests that strict-mode, lease and high-water mark keys use the prefix
+func TestKeyPrefixCoversEveryKey(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+	store, err := snowflake.NewRedisWatermarkStore(client)
+	if err != nil {
+		t.Fatalf("Failed to create watermark store: %v", err)
+	}
+
+	sf, err := snowflake.NewBuilder().
+		SetRedisClient(client).
+		SetKeyPrefix("{app}").
+		SetDatacenterID(2).
+		SetWorkerID(3).
+		SetStrictMode(true).
+		SetLeaseTTL(time.Minute).
+		SetWatermarkStore(store).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with prefix: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+
+	keys, _, err := client.Scan(ctx, 0, "*", 100)
+	if err != nil {
+		t.Fatalf("Failed to scan keys: %v", err)
+	}
+	want := map[string]bool{
+		"{app}:slot:2:3":                        false,
+		"{app}:hwm:2:3":                         false,
+		"{app}:first_use:2:3":                   false,
+		"{app}:id:" + strconv.FormatInt(id, 10): false,
+	}
+	for _, key := range keys {
+		if _, ok := want[key]; !ok {
+			t.Errorf("Unexpected key %s", key)
+		}
+		want[key] = true
+	}
+	for key, found := range want {
+		if !found {
+			t.Errorf("Missing key %s", key)
+		}
+	}
+
+	admin, err := snowflake.NewAdmin(client)
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	if slots, _ := admin.ListSlots(ctx); len(slots) != 0 {
+		t.Errorf("Default prefix should see no slots, got %+v", slots)
+	}
+	if slots, _ := admin.SetKeyPrefix("{app}").ListSlots(ctx); len(slots) != 1 {
+		t.Errorf("Expected one slot under {app}, got %+v", slots)
+	}
+}
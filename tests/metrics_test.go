@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/server"
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// TestStatsCounters tests the generation, rollback and strict-mode counters
+func TestStatsCounters(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+	sf, err := snowflake.NewBuilder().SetClock(clock).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with mock clock: %v", err)
+	}
+	defer sf.Cleanup()
+
+	for i := 0; i < 10; i++ {
+		if _, err := sf.Generate(); err != nil {
+			t.Fatalf("Failed to generate ID: %v", err)
+		}
+	}
+	if _, err := sf.GenerateN(5); err != nil {
+		t.Fatalf("Failed to generate batch: %v", err)
+	}
+	clock.Advance(-time.Millisecond)
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrClockRollback) {
+		t.Fatalf("Expected ErrClockRollback, got %v", err)
+	}
+
+	stats := sf.Stats()
+	if stats.Generated != 15 || stats.ClockRollbacks != 1 {
+		t.Errorf("Expected 15 generated and 1 rollback, got %d and %d", stats.Generated, stats.ClockRollbacks)
+	}
+	if stats.LeaseTTL != 0 || stats.RedisLatency.Count != 0 {
+		t.Errorf("Local instance should have no lease or Redis calls, got %+v", stats)
+	}
+
+	// In strict mode a frozen clock yields the same first candidate, which Redis already holds
+	client := mock.NewMockRedisClient()
+	strict, err := snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(1).SetWorkerID(1).
+		SetStrictMode(true).SetClock(clock).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize in strict mode: %v", err)
+	}
+	defer strict.Cleanup()
+	for i := 0; i < 2; i++ {
+		if _, err := strict.Generate(); err != nil {
+			t.Fatalf("Failed to generate ID in strict mode: %v", err)
+		}
+	}
+	stats = strict.Stats()
+	if stats.Generated != 2 || stats.StrictRetries != 1 || stats.RedisLatency.Count != 3 || stats.RedisErrors != 0 {
+		t.Errorf("Unexpected strict-mode stats %+v", stats)
+	}
+}
+
+// TestMetricsHandler tests the Prometheus exposition of a leased instance
+func TestMetricsHandler(t *testing.T) {
+	sf, err := snowflake.NewBuilder().SetRedisClient(mock.NewMockRedisClient()).SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+	defer sf.Cleanup()
+	if _, err := sf.GenerateN(3); err != nil {
+		t.Fatalf("Failed to generate batch: %v", err)
+	}
+	if ttl := sf.Stats().LeaseTTL; ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected the remaining lease TTL, got %v", ttl)
+	}
+
+	rec := httptest.NewRecorder()
+	server.NewHTTPHandler(sf).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"# TYPE snowflake_ids_generated_total counter\nsnowflake_ids_generated_total 3\n",
+		`snowflake_info{datacenter_id="0",worker_id="0",exhaustion_policy="spin"} 1`,
+		"# TYPE snowflake_redis_latency_seconds histogram\n",
+		`snowflake_redis_latency_seconds_bucket{le="0.0005"} `,
+		`snowflake_redis_latency_seconds_bucket{le="+Inf"} 0`,
+		"snowflake_redis_errors_total 0\n",
+		"snowflake_lease_ttl_seconds ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics missing %q:\n%s", want, body)
+		}
+	}
+}
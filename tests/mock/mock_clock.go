@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock Manually driven clock for deterministic tests
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewMockClock Creates a mock clock set to the given time
+func NewMockClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now Returns the current mock time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set Sets the mock time, which may move backwards
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance Moves the mock time by d, which may be negative
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
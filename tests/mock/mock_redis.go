@@ -3,36 +3,84 @@ package mock
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/sunquakes/snowredis/redis"
 )
 
 // RedisClient Mock Redis client for testing
 type RedisClient struct {
-	data map[string]interface{}
+	mu         sync.Mutex
+	data       map[string]interface{}
+	expiry     map[string]time.Time
+	timeOffset time.Duration
+	scans      map[uint64]string // Last key returned by each open SCAN cursor
+	nextScan   uint64
+	failure    error // Returned by every operation while set
 }
 
 // NewMockRedisClient Creates a mock Redis client
 func NewMockRedisClient() *RedisClient {
 	return &RedisClient{
-		data: make(map[string]interface{}),
+		data:   make(map[string]interface{}),
+		expiry: make(map[string]time.Time),
+		scans:  make(map[uint64]string),
+	}
+}
+
+// lookup Returns the value of a key, dropping it first if it has expired (lock must be held)
+func (m *RedisClient) lookup(key string) (interface{}, bool) {
+	if deadline, ok := m.expiry[key]; ok && !time.Now().Before(deadline) {
+		delete(m.data, key)
+		delete(m.expiry, key)
+	}
+	v, exists := m.data[key]
+	return v, exists
+}
+
+// store Sets the value and expiration of a key (lock must be held)
+func (m *RedisClient) store(key string, value interface{}, expiration time.Duration) {
+	m.data[key] = value
+	if expiration > 0 {
+		m.expiry[key] = time.Now().Add(expiration)
+	} else {
+		delete(m.expiry, key)
 	}
 }
 
 // SetNX Sets value only if key does not exist
-func (m *RedisClient) SetNX(_ context.Context, key string, value interface{}, _ time.Duration) (bool, error) {
-	_, exists := m.data[key]
-	if exists {
+func (m *RedisClient) SetNX(_ context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return false, m.failure
+	}
+	if _, exists := m.lookup(key); exists {
 		return false, nil
 	}
-	m.data[key] = value
+	m.store(key, value, expiration)
 	return true, nil
 }
 
 // Incr Increments the value of a key
 func (m *RedisClient) Incr(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return 0, m.failure
+	}
 	var val int64 = 1
-	if v, exists := m.data[key]; exists {
-		val = v.(int64) + 1
+	if v, exists := m.lookup(key); exists {
+		current, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value is not an integer: %w", err)
+		}
+		val = current + 1
 	}
 	m.data[key] = val
 	return val, nil
@@ -40,12 +88,172 @@ func (m *RedisClient) Incr(_ context.Context, key string) (int64, error) {
 
 // Del Deletes keys
 func (m *RedisClient) Del(_ context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return 0, m.failure
+	}
 	count := 0
 	for _, key := range keys {
-		if _, exists := m.data[key]; exists {
+		if _, exists := m.lookup(key); exists {
 			delete(m.data, key)
+			delete(m.expiry, key)
 			count++
 		}
 	}
 	return int64(count), nil
 }
+
+// CompareAndDelete Deletes a key only while it holds the given value
+func (m *RedisClient) CompareAndDelete(_ context.Context, key string, value string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return false, m.failure
+	}
+	v, exists := m.lookup(key)
+	if !exists || fmt.Sprint(v) != value {
+		return false, nil
+	}
+	delete(m.data, key)
+	delete(m.expiry, key)
+	return true, nil
+}
+
+// CompareAndExpire Sets a timeout on a key only while it holds the given value
+func (m *RedisClient) CompareAndExpire(_ context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return false, m.failure
+	}
+	v, exists := m.lookup(key)
+	if !exists || fmt.Sprint(v) != value {
+		return false, nil
+	}
+	m.expiry[key] = time.Now().Add(expiration)
+	return true, nil
+}
+
+// Get Gets the value of a key
+func (m *RedisClient) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return "", m.failure
+	}
+	v, exists := m.lookup(key)
+	if !exists {
+		return "", redis.ErrNil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// Set Sets the value of a key
+func (m *RedisClient) Set(_ context.Context, key string, value interface{}, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return m.failure
+	}
+	m.store(key, value, expiration)
+	return nil
+}
+
+// Expire Sets a timeout on a key
+func (m *RedisClient) Expire(_ context.Context, key string, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return false, m.failure
+	}
+	if _, exists := m.lookup(key); !exists {
+		return false, nil
+	}
+	m.expiry[key] = time.Now().Add(expiration)
+	return true, nil
+}
+
+// TTL Returns the remaining time to live of a key
+func (m *RedisClient) TTL(_ context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return 0, m.failure
+	}
+	if _, exists := m.lookup(key); !exists {
+		return 0, redis.ErrNil
+	}
+	deadline, ok := m.expiry[key]
+	if !ok {
+		return -1, nil
+	}
+	return time.Until(deadline), nil
+}
+
+// Scan Returns a page of keys matching the pattern in sorted order
+// Like Redis SCAN, keys present for the whole iteration are returned even if others are deleted in between
+func (m *RedisClient) Scan(_ context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return nil, 0, m.failure
+	}
+	after, resumed := m.scans[cursor]
+	delete(m.scans, cursor)
+
+	var keys []string
+	for key := range m.data {
+		if _, exists := m.lookup(key); !exists || (resumed && key <= after) {
+			continue
+		}
+		if ok, err := path.Match(match, key); err != nil {
+			return nil, 0, err
+		} else if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if count <= 0 {
+		count = 10
+	}
+	if int64(len(keys)) <= count {
+		return keys, 0, nil
+	}
+	keys = keys[:count]
+	m.nextScan++
+	m.scans[m.nextScan] = keys[len(keys)-1]
+	return keys, m.nextScan, nil
+}
+
+// Time Returns the local time shifted by the configured offset
+func (m *RedisClient) Time(_ context.Context) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failure != nil {
+		return time.Time{}, m.failure
+	}
+	return time.Now().Add(m.timeOffset), nil
+}
+
+// Ping Reports the configured failure, if any
+func (m *RedisClient) Ping(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failure
+}
+
+// SetFailure Makes every operation return err, simulating an unreachable server (nil restores it)
+func (m *RedisClient) SetFailure(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failure = err
+}
+
+// SetTimeOffset Shifts the server time returned by Time, simulating local clock skew
+func (m *RedisClient) SetTimeOffset(offset time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeOffset = offset
+}
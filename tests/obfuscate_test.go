@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+var (
+	oldSecret = []byte("0123456789abcdef-old")
+	newSecret = []byte("0123456789abcdef-new")
+)
+
+// idAt composes an ID of the default layout at a time
+func idAt(t time.Time, node, sequence int64) snowflake.ID {
+	offset := t.UnixNano()/int64(time.Millisecond) - snowflake.Epoch
+	return snowflake.ID(offset<<22 | node<<12 | sequence)
+}
+
+// TestObfuscateRoundTrip tests that obfuscation is reversible and hides the order of consecutive IDs
+func TestObfuscateRoundTrip(t *testing.T) {
+	o, err := snowflake.NewObfuscator(snowflake.DefaultLayout, snowflake.ObfuscationKey{Secret: oldSecret})
+	if err != nil {
+		t.Fatalf("Failed to create obfuscator: %v", err)
+	}
+	now := time.Now()
+	r := rand.New(rand.NewSource(3))
+	ids := []snowflake.ID{0, 1, idAt(now, 0, 0), idAt(now, 0, 1), idAt(now, 0, 2)}
+	for i := 0; i < 1000; i++ {
+		ids = append(ids, idAt(now.Add(-time.Duration(r.Int63n(int64(24*365*time.Hour)))), r.Int63n(1024), r.Int63n(4096)))
+	}
+
+	increasing := 0
+	var last snowflake.ID
+	for i, id := range ids {
+		hidden, err := o.Obfuscate(id)
+		if err != nil || hidden < 0 {
+			t.Fatalf("Failed to obfuscate %d: %d, %v", id, hidden, err)
+		}
+		if hidden == id {
+			t.Errorf("%d was left unchanged", id)
+		}
+		got, err := o.Deobfuscate(hidden)
+		if err != nil || got != id {
+			t.Fatalf("%d round-tripped through %d to %d: %v", id, hidden, got, err)
+		}
+		if i > 0 && hidden > last {
+			increasing++
+		}
+		last = hidden
+	}
+	if increasing < 400 || increasing > 600 {
+		t.Errorf("Obfuscated IDs look ordered: %d of %d increase", increasing, len(ids)-1)
+	}
+
+	other, _ := snowflake.NewObfuscator(snowflake.DefaultLayout, snowflake.ObfuscationKey{Secret: newSecret})
+	a, _ := o.Obfuscate(ids[2])
+	b, _ := other.Obfuscate(ids[2])
+	if a == b {
+		t.Error("Different keys gave the same obfuscated ID")
+	}
+	if _, err := o.Obfuscate(-1); !errors.Is(err, snowflake.ErrInvalidID) {
+		t.Errorf("Expected ErrInvalidID for a negative ID, got %v", err)
+	}
+}
+
+// TestObfuscateCollisionFree tests a dense block of IDs across two key rotations for collisions
+func TestObfuscateCollisionFree(t *testing.T) {
+	layout := snowflake.Layout{Epoch: snowflake.Epoch, DatacenterBits: 1, WorkerBits: 1, SequenceBits: 3}
+	start := time.UnixMilli(snowflake.Epoch + 1000)
+	o, err := snowflake.NewObfuscator(layout,
+		snowflake.ObfuscationKey{Secret: oldSecret},
+		snowflake.ObfuscationKey{Secret: newSecret, Since: start},
+		snowflake.ObfuscationKey{Secret: oldSecret, Since: start.Add(3 * time.Millisecond)},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create obfuscator: %v", err)
+	}
+
+	// 32 IDs per millisecond, from well before the first rotation to well after the second
+	seen := make(map[snowflake.ID]bool)
+	for id := snowflake.ID(990 << 5); id < 1010<<5; id++ {
+		hidden, err := o.Obfuscate(id)
+		if err != nil {
+			t.Fatalf("Failed to obfuscate %d: %v", id, err)
+		}
+		if seen[hidden] {
+			t.Fatalf("%d collided with another ID at %d", id, hidden)
+		}
+		seen[hidden] = true
+		if got, err := o.Deobfuscate(hidden); err != nil || got != id {
+			t.Fatalf("%d round-tripped to %d: %v", id, got, err)
+		}
+	}
+}
+
+// TestObfuscateKeyRotation tests that rotating keys keeps published IDs and uses the new key afterwards
+func TestObfuscateKeyRotation(t *testing.T) {
+	now := time.Now()
+	rotation := now.Add(-time.Hour)
+	before := idAt(rotation.Add(-time.Minute), 5, 7)
+	after := idAt(now, 5, 7)
+
+	single, err := snowflake.NewObfuscator(snowflake.DefaultLayout, snowflake.ObfuscationKey{Secret: oldSecret})
+	if err != nil {
+		t.Fatalf("Failed to create obfuscator: %v", err)
+	}
+	rotated, err := snowflake.NewObfuscator(snowflake.DefaultLayout,
+		snowflake.ObfuscationKey{Secret: newSecret, Since: rotation},
+		snowflake.ObfuscationKey{Secret: oldSecret},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create rotated obfuscator: %v", err)
+	}
+
+	published, _ := single.Obfuscate(before)
+	if got, _ := rotated.Obfuscate(before); got != published {
+		t.Errorf("Rotation changed the published form of %d: %d != %d", before, got, published)
+	}
+	if got, err := rotated.Deobfuscate(published); err != nil || got != before {
+		t.Errorf("Failed to deobfuscate a published ID after rotation: %d, %v", got, err)
+	}
+
+	oldForm, _ := single.Obfuscate(after)
+	newForm, _ := rotated.Obfuscate(after)
+	if oldForm == newForm {
+		t.Error("IDs after the rotation should use the new key")
+	}
+	if got, err := rotated.Deobfuscate(newForm); err != nil || got != after {
+		t.Errorf("Failed to deobfuscate a new ID: %d, %v", got, err)
+	}
+}
+
+// TestDeobfuscateRejectsImplausible tests that values decoding to the future are refused
+func TestDeobfuscateRejectsImplausible(t *testing.T) {
+	clock := mock.NewMockClock(time.Now())
+	o, err := snowflake.NewObfuscator(snowflake.DefaultLayout, snowflake.ObfuscationKey{Secret: oldSecret})
+	if err != nil {
+		t.Fatalf("Failed to create obfuscator: %v", err)
+	}
+	o.SetClock(clock)
+
+	future, _ := o.Obfuscate(idAt(clock.Now().Add(time.Hour), 0, 0))
+	if _, err := o.Deobfuscate(future); !errors.Is(err, snowflake.ErrInvalidID) {
+		t.Errorf("Expected ErrInvalidID for a future ID, got %v", err)
+	}
+	clock.Advance(2 * time.Hour)
+	if _, err := o.Deobfuscate(future); err != nil {
+		t.Errorf("Expected the ID to be accepted once its time has come, got %v", err)
+	}
+
+	// Most random values decode far into the future
+	r := rand.New(rand.NewSource(4))
+	rejected := 0
+	for i := 0; i < 1000; i++ {
+		if _, err := o.Deobfuscate(snowflake.ID(r.Int63n(math.MaxInt64))); err != nil {
+			rejected++
+		}
+	}
+	if rejected < 800 {
+		t.Errorf("Only %d of 1000 random values were rejected", rejected)
+	}
+}
+
+// TestObfuscatorKeyValidation tests the key ring checks
+func TestObfuscatorKeyValidation(t *testing.T) {
+	since := time.Now()
+	for name, keys := range map[string][]snowflake.ObfuscationKey{
+		"no keys":      nil,
+		"short secret": {{Secret: []byte("short")}},
+		"same since": {
+			{Secret: oldSecret, Since: since},
+			{Secret: newSecret, Since: since},
+		},
+	} {
+		if _, err := snowflake.NewObfuscator(snowflake.DefaultLayout, keys...); !errors.Is(err, snowflake.ErrInvalidObfuscationKey) {
+			t.Errorf("%s: expected ErrInvalidObfuscationKey, got %v", name, err)
+		}
+	}
+}
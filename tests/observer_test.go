@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// recordingObserver Sends a description of every event to a channel
+type recordingObserver struct {
+	snowflake.NopObserver
+	events chan string
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{events: make(chan string, 100)}
+}
+
+func (o *recordingObserver) OnAllocated(datacenterID, workerID int64) {
+	o.events <- fmt.Sprintf("allocated %d/%d", datacenterID, workerID)
+}
+
+func (o *recordingObserver) OnClockRollback(drift time.Duration) {
+	o.events <- fmt.Sprintf("rollback %v", drift)
+}
+
+func (o *recordingObserver) OnSequenceExhausted(wait time.Duration) {
+	o.events <- fmt.Sprintf("exhausted %v", wait)
+}
+
+func (o *recordingObserver) OnLeaseRenewFailed(error) {
+	o.events <- "renew failed"
+}
+
+func (o *recordingObserver) OnLeaseLost() {
+	o.events <- "lease lost"
+}
+
+func (o *recordingObserver) OnStrictFallback(err error) {
+	o.events <- "fallback " + err.Error()
+}
+
+// expect waits for the next event and compares it
+func (o *recordingObserver) expect(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-o.events:
+		if got != want {
+			t.Errorf("Expected event %q, got %q", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for event %q", want)
+	}
+}
+
+// TestObserverGenerationEvents tests the allocation, rollback and exhaustion callbacks
+func TestObserverGenerationEvents(t *testing.T) {
+	observer := newRecordingObserver()
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+	sf, err := snowflake.NewBuilder().SetDatacenterID(2).SetWorkerID(3).SetClock(clock).
+		SetExhaustionPolicy(snowflake.ExhaustionError).SetObserver(observer).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with observer: %v", err)
+	}
+	defer sf.Cleanup()
+	observer.expect(t, "allocated 2/3")
+
+	if _, err := sf.GenerateN(int(snowflake.DefaultLayout.MaxSequence()) + 1); err != nil {
+		t.Fatalf("Failed to generate a full millisecond: %v", err)
+	}
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrOverFlow) {
+		t.Fatalf("Expected ErrOverFlow, got %v", err)
+	}
+	observer.expect(t, "exhausted 0s")
+
+	clock.Advance(-5 * time.Millisecond)
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrClockRollback) {
+		t.Fatalf("Expected ErrClockRollback, got %v", err)
+	}
+	observer.expect(t, "rollback 5ms")
+}
+
+// TestObserverStrictFallback tests that strict mode without a Redis client reports its local fallback once
+func TestObserverStrictFallback(t *testing.T) {
+	observer := newRecordingObserver()
+	sf, err := snowflake.NewBuilder().SetDatacenterID(1).SetWorkerID(1).
+		SetStrictMode(true).SetObserver(observer).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize in strict mode: %v", err)
+	}
+	defer sf.Cleanup()
+	observer.expect(t, "allocated 1/1")
+	observer.expect(t, "fallback "+snowflake.ErrStrictWithoutRedis.Error())
+
+	if _, err := sf.Generate(); err != nil {
+		t.Fatalf("Failed to generate locally: %v", err)
+	}
+
+	// With a Redis client strict mode fails while Redis is down instead of falling back
+	client := mock.NewMockRedisClient()
+	strict, err := snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(1).SetWorkerID(2).
+		SetStrictMode(true).SetObserver(observer).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize in strict mode: %v", err)
+	}
+	defer strict.Cleanup()
+	observer.expect(t, "allocated 1/2")
+	client.SetFailure(errors.New("connection refused"))
+	if _, err := strict.Generate(); err == nil {
+		t.Error("Expected strict mode to fail while Redis is down")
+	}
+	select {
+	case got := <-observer.events:
+		t.Errorf("Unexpected event %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestObserverLeaseLost tests the renewal failure and lease loss callbacks
+func TestObserverLeaseLost(t *testing.T) {
+	observer := newRecordingObserver()
+	client := mock.NewMockRedisClient()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetDatacenterID(1).SetWorkerID(1).
+		SetLeaseTTL(30 * time.Millisecond).SetLogger(&recordingLogger{}).SetObserver(observer).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+	defer sf.Cleanup()
+	observer.expect(t, "allocated 1/1")
+
+	if err := client.Set(context.Background(), "snowflake:slot:1:1", "someone-else", 0); err != nil {
+		t.Fatalf("Failed to overwrite slot key: %v", err)
+	}
+	observer.expect(t, "renew failed")
+	observer.expect(t, "lease lost")
+	observer.expect(t, "renew failed")
+}
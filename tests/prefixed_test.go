@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// TestPrefixRegistry tests generating and parsing type-tagged IDs
+func TestPrefixRegistry(t *testing.T) {
+	layout := snowflake.Layout{Epoch: snowflake.Epoch, DatacenterBits: 3, WorkerBits: 3, SequenceBits: 10}
+	users, err := snowflake.NewBuilder().SetLayout(layout).SetDatacenterID(2).SetWorkerID(5).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer users.Cleanup()
+
+	registry := snowflake.NewPrefixRegistry()
+	if err := registry.Register("usr", users); err != nil {
+		t.Fatalf("Failed to register usr: %v", err)
+	}
+	if err := registry.Register("ord", snowflake.NewSequentialGenerator(1)); err != nil {
+		t.Fatalf("Failed to register ord: %v", err)
+	}
+
+	s, err := registry.New("usr")
+	if err != nil {
+		t.Fatalf("Failed to generate prefixed ID: %v", err)
+	}
+	if !strings.HasPrefix(s, "usr_") || len(s) != len("usr_")+11 {
+		t.Fatalf("Unexpected prefixed ID %q", s)
+	}
+	parsed, err := registry.ParseAs("usr", s)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", s, err)
+	}
+	if parsed.Prefix != "usr" || parsed.String() != s || parsed.ID.Base62() != s[4:] {
+		t.Errorf("Unexpected parse result %+v", parsed)
+	}
+	if parsed.Components.DatacenterID != 2 || parsed.Components.WorkerID != 5 {
+		t.Errorf("Components should be decoded with the generator's layout, got %+v", parsed.Components)
+	}
+
+	order, _ := registry.New("ord")
+	if order != "ord_00000000001" {
+		t.Errorf("Unexpected order ID %q", order)
+	}
+	if _, err := registry.ParseAs("usr", order); !errors.Is(err, snowflake.ErrPrefixMismatch) {
+		t.Errorf("Expected ErrPrefixMismatch, got %v", err)
+	}
+	if p, err := registry.Parse(order); err != nil || p.Prefix != "ord" || p.ID != 1 {
+		t.Errorf("Unexpected parse of %q: %+v, %v", order, p, err)
+	}
+
+	for input, want := range map[string]error{
+		"acct_00000000001": snowflake.ErrUnknownPrefix,
+		"usr00000000001":   snowflake.ErrInvalidID,
+		"usr_":             snowflake.ErrInvalidID,
+		"usr_0000000000-1": snowflake.ErrInvalidID,
+	} {
+		if _, err := registry.Parse(input); !errors.Is(err, want) {
+			t.Errorf("Parse %q: expected %v, got %v", input, want, err)
+		}
+	}
+	if _, err := registry.New("acct"); !errors.Is(err, snowflake.ErrUnknownPrefix) {
+		t.Errorf("Expected ErrUnknownPrefix, got %v", err)
+	}
+	for _, prefix := range []string{"usr", "", "Usr", "1st", "us_r", "us-r"} {
+		if err := registry.Register(prefix, users); !errors.Is(err, snowflake.ErrInvalidPrefix) {
+			t.Errorf("Register %q: expected ErrInvalidPrefix, got %v", prefix, err)
+		}
+	}
+}
+
+var registerTestPrefix sync.Once
+
+// TestDefaultPrefixRegistry tests the package-level functions
+func TestDefaultPrefixRegistry(t *testing.T) {
+	registerTestPrefix.Do(func() {
+		if err := snowflake.RegisterPrefix("test", snowflake.NewSequentialGenerator(62)); err != nil {
+			t.Fatalf("Failed to register prefix: %v", err)
+		}
+	})
+	s, err := snowflake.NewPrefixed("test")
+	if err != nil {
+		t.Fatalf("Failed to generate prefixed ID: %v", err)
+	}
+	if p, err := snowflake.ParsePrefixed(s); err != nil || p.Prefix != "test" {
+		t.Errorf("Unexpected parse of %q: %+v, %v", s, p, err)
+	}
+	if _, err := snowflake.ParsePrefixedAs("usr", s); !errors.Is(err, snowflake.ErrPrefixMismatch) {
+		t.Errorf("Expected ErrPrefixMismatch, got %v", err)
+	}
+}
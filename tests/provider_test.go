@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// nodeOf extracts the datacenter ID and worker ID from an ID in the default layout
+func nodeOf(id int64) (int64, int64) {
+	return (id >> 17) & 31, (id >> 12) & 31
+}
+
+// TestIPv4Provider tests deriving the node from the low bits of an IPv4 address
+func TestIPv4Provider(t *testing.T) {
+	// 10.0.1.70 -> low 10 bits are 0b01_0100_0110 = 326 -> datacenter 10, worker 6
+	sf, err := snowflake.NewBuilder().
+		SetWorkerIDProvider(snowflake.IPv4Provider{IP: net.ParseIP("10.0.1.70")}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build with IPv4 provider: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	if dc, w := nodeOf(id); dc != 10 || w != 6 {
+		t.Errorf("Expected node 10/6, got %d/%d", dc, w)
+	}
+}
+
+// TestIPv4ProviderRejectsIPv6 tests that an IPv6-only address is rejected
+func TestIPv4ProviderRejectsIPv6(t *testing.T) {
+	_, err := snowflake.NewBuilder().
+		SetWorkerIDProvider(snowflake.IPv4Provider{IP: net.ParseIP("2001:db8::1")}).
+		Build()
+	if !errors.Is(err, snowflake.ErrNoIPv4Address) {
+		t.Errorf("Expected ErrNoIPv4Address, got %v", err)
+	}
+}
+
+// TestStatefulSetProvider tests deriving the node from a StatefulSet ordinal
+func TestStatefulSetProvider(t *testing.T) {
+	sf, err := snowflake.NewBuilder().
+		SetWorkerIDProvider(snowflake.StatefulSetProvider{Hostname: "id-service-37"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build with StatefulSet provider: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	if dc, w := nodeOf(id); dc != 1 || w != 5 {
+		t.Errorf("Expected node 1/5, got %d/%d", dc, w)
+	}
+}
+
+// TestStatefulSetProviderErrors tests hostnames without an ordinal and ordinals beyond the layout
+func TestStatefulSetProviderErrors(t *testing.T) {
+	_, err := snowflake.NewBuilder().
+		SetWorkerIDProvider(snowflake.StatefulSetProvider{Hostname: "id-service"}).
+		Build()
+	if !errors.Is(err, snowflake.ErrNoOrdinal) {
+		t.Errorf("Expected ErrNoOrdinal, got %v", err)
+	}
+
+	// 32 datacenters * 32 workers = 1024 nodes, ordinal 1024 does not fit
+	_, err = snowflake.NewBuilder().
+		SetWorkerIDProvider(snowflake.StatefulSetProvider{Hostname: "id-service-1024"}).
+		Build()
+	if !errors.Is(err, snowflake.ErrInvalidDatacenter) {
+		t.Errorf("Expected ErrInvalidDatacenter, got %v", err)
+	}
+}
+
+// TestEnvProvider tests reading the node from environment variables
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("TEST_DC", "3")
+	t.Setenv("TEST_WORKER", "17")
+
+	sf, err := snowflake.NewBuilder().
+		SetWorkerIDProvider(snowflake.EnvProvider{DatacenterVar: "TEST_DC", WorkerVar: "TEST_WORKER"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build with env provider: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	if dc, w := nodeOf(id); dc != 3 || w != 17 {
+		t.Errorf("Expected node 3/17, got %d/%d", dc, w)
+	}
+
+	t.Setenv("TEST_WORKER", "32")
+	_, err = snowflake.NewBuilder().
+		SetWorkerIDProvider(snowflake.EnvProvider{DatacenterVar: "TEST_DC", WorkerVar: "TEST_WORKER"}).
+		Build()
+	if !errors.Is(err, snowflake.ErrInvalidWorker) {
+		t.Errorf("Expected ErrInvalidWorker, got %v", err)
+	}
+}
+
+// TestHostnameHashProvider tests that the hostname hash is stable and takes precedence over Redis allocation
+func TestHostnameHashProvider(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+	provider := snowflake.HostnameHashProvider{Hostname: "edge-node-a.example.com"}
+
+	first, err := snowflake.NewBuilder().SetWorkerIDProvider(provider).Build()
+	if err != nil {
+		t.Fatalf("Failed to build with hostname hash provider: %v", err)
+	}
+	defer first.Cleanup()
+
+	second, err := snowflake.NewBuilder().SetRedisClient(mockRedis).SetWorkerIDProvider(provider).Build()
+	if err != nil {
+		t.Fatalf("Failed to build with hostname hash provider and Redis: %v", err)
+	}
+	defer second.Cleanup()
+
+	id1, _ := first.Generate()
+	id2, _ := second.Generate()
+	dc1, w1 := nodeOf(id1)
+	dc2, w2 := nodeOf(id2)
+	if dc1 != dc2 || w1 != w2 {
+		t.Errorf("Expected stable node, got %d/%d and %d/%d", dc1, w1, dc2, w2)
+	}
+
+	// The provider is used instead of Redis allocation, so no counter was incremented
+	if n, _ := mockRedis.Incr(context.Background(), "snowflake:next_worker_id"); n != 1 {
+		t.Errorf("Expected Redis allocation to be skipped, counter is %d", n)
+	}
+}
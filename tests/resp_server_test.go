@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sunquakes/snowredis/server"
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// startRESPServer serves a local generator on a loopback port and returns a connection to it
+func startRESPServer(t *testing.T) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	sf, err := snowflake.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	t.Cleanup(sf.Cleanup)
+	return serveRESP(t, sf)
+}
+
+// serveRESP serves the generator on a loopback port and returns a connection to it
+func serveRESP(t *testing.T, generator server.Generator) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := server.NewRESPServer(generator)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+	t.Cleanup(func() {
+		_ = srv.Close()
+		if err := <-done; !errors.Is(err, server.ErrServerClosed) {
+			t.Errorf("Expected ErrServerClosed from Serve, got %v", err)
+		}
+	})
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, bufio.NewReader(conn)
+}
+
+// sendCommands writes the commands as RESP arrays in a single write
+func sendCommands(t *testing.T, conn net.Conn, cmds ...[]string) {
+	t.Helper()
+	var b strings.Builder
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "*%d\r\n", len(cmd))
+		for _, arg := range cmd {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		t.Fatalf("Failed to write commands: %v", err)
+	}
+}
+
+// readReply reads one RESP reply, flattening arrays into a slice of their elements
+func readReply(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read reply: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	switch line[0] {
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		var elems []string
+		for i := 0; i < n; i++ {
+			elems = append(elems, readReply(t, r)...)
+		}
+		return elems
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatalf("Failed to read bulk string: %v", err)
+		}
+		return []string{string(buf[:n])}
+	}
+	return []string{line}
+}
+
+// TestRESPServerCommands tests every supported command and the error replies
+func TestRESPServerCommands(t *testing.T) {
+	conn, r := startRESPServer(t)
+
+	sendCommands(t, conn, []string{"PING"})
+	if reply := readReply(t, r); reply[0] != "+PONG" {
+		t.Errorf("Expected +PONG, got %q", reply)
+	}
+	sendCommands(t, conn, []string{"ping", "hello"})
+	if reply := readReply(t, r); reply[0] != "hello" {
+		t.Errorf("Expected echoed message, got %q", reply)
+	}
+
+	sendCommands(t, conn, []string{"NEXTID"})
+	reply := readReply(t, r)
+	if !strings.HasPrefix(reply[0], ":") {
+		t.Fatalf("Expected integer reply, got %q", reply)
+	}
+	id := reply[0][1:]
+
+	sendCommands(t, conn, []string{"NEXTIDS", "3"})
+	if reply := readReply(t, r); len(reply) != 3 {
+		t.Errorf("Expected 3 IDs, got %q", reply)
+	}
+
+	sendCommands(t, conn, []string{"DECODE", id})
+	decoded := readReply(t, r)
+	if len(decoded) != 10 || decoded[4] != "datacenter_id" || decoded[5] != ":1" || decoded[7] != ":1" {
+		t.Errorf("Unexpected DECODE reply %q", decoded)
+	}
+
+	sendCommands(t, conn, []string{"INFO"})
+	if info := readReply(t, r); !strings.Contains(info[0], "worker_id:1\r\n") || !strings.Contains(info[0], "sequence_bits:12\r\n") {
+		t.Errorf("Unexpected INFO reply %q", info)
+	}
+
+	for _, cmd := range [][]string{{"NEXTIDS", "0"}, {"NEXTIDS"}, {"DECODE", "x"}, {"FLUSHALL"}} {
+		sendCommands(t, conn, cmd)
+		if reply := readReply(t, r); !strings.HasPrefix(reply[0], "-ERR") {
+			t.Errorf("Expected error for %q, got %q", cmd, reply)
+		}
+	}
+
+	// Inline commands work as in telnet
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		t.Fatalf("Failed to write inline command: %v", err)
+	}
+	if reply := readReply(t, r); reply[0] != "+PONG" {
+		t.Errorf("Expected +PONG for inline PING, got %q", reply)
+	}
+
+	sendCommands(t, conn, []string{"QUIT"})
+	if reply := readReply(t, r); reply[0] != "+OK" {
+		t.Errorf("Expected +OK for QUIT, got %q", reply)
+	}
+	if _, err := r.ReadByte(); err == nil {
+		t.Error("Connection should be closed after QUIT")
+	}
+}
+
+// TestRESPServerPipeline tests that pipelined commands are answered in order with increasing IDs
+func TestRESPServerPipeline(t *testing.T) {
+	conn, r := startRESPServer(t)
+
+	cmds := [][]string{{"NEXTID"}, {"NEXTIDS", "5"}, {"PING"}, {"NEXTID"}, {"NEXTIDS", "2"}}
+	sendCommands(t, conn, cmds...)
+
+	var ids []int64
+	for i, cmd := range cmds {
+		for _, elem := range readReply(t, r) {
+			if cmd[0] == "PING" {
+				if elem != "+PONG" {
+					t.Errorf("Reply %d: expected +PONG, got %q", i, elem)
+				}
+				continue
+			}
+			id, err := strconv.ParseInt(strings.TrimPrefix(elem, ":"), 10, 64)
+			if err != nil {
+				t.Fatalf("Reply %d: expected integer, got %q", i, elem)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) != 9 {
+		t.Fatalf("Expected 9 IDs, got %d", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("IDs not increasing at %d: %d <= %d", i, ids[i], ids[i-1])
+		}
+	}
+}
+
+// TestRESPServerFakeGenerator tests serving a generator other than RedisSnowflake
+func TestRESPServerFakeGenerator(t *testing.T) {
+	conn, r := serveRESP(t, &fakeGenerator{})
+
+	sendCommands(t, conn, []string{"NEXTID"}, []string{"NEXTIDS", "2"}, []string{"INFO"})
+	if reply := readReply(t, r); reply[0] != ":1" {
+		t.Errorf("Expected :1, got %q", reply)
+	}
+	if reply := readReply(t, r); len(reply) != 2 || reply[0] != ":2" || reply[1] != ":3" {
+		t.Errorf("Expected :2 and :3, got %q", reply)
+	}
+	if reply := readReply(t, r); strings.Contains(reply[0], "datacenter_id") {
+		t.Errorf("Expected INFO without statistics for a generator without Stats, got %q", reply)
+	}
+}
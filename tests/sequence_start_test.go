@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// TestSequenceStartRandomSpreadsShards tests that low-traffic IDs spread evenly over id % N
+func TestSequenceStartRandomSpreadsShards(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().
+		SetClock(clock).
+		SetSequenceStart(snowflake.SequenceStartRandom).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	// One ID per millisecond, which always has sequence 0 with the default start
+	shards := make([]int, 4)
+	for i := 0; i < 4000; i++ {
+		id, err := sf.Generate()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: %v", err)
+		}
+		shards[id%4]++
+		clock.Advance(time.Millisecond)
+	}
+	for shard, count := range shards {
+		if count < 800 || count > 1200 {
+			t.Errorf("Shard %d received %d of 4000 IDs, distribution is skewed: %v", shard, count, shards)
+		}
+	}
+}
+
+// TestSequenceStartRotating tests that each millisecond starts one past the previous start
+func TestSequenceStartRotating(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().
+		SetClock(clock).
+		SetSequenceStart(snowflake.SequenceStartRotating).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	for i := int64(1); i <= 5; i++ {
+		id, err := sf.Generate()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: %v", err)
+		}
+		if seq := id & 4095; seq != i {
+			t.Errorf("Expected sequence %d, got %d", i, seq)
+		}
+		clock.Advance(time.Millisecond)
+	}
+}
+
+// TestSequenceStartWrapKeepsUniqueness tests that a random start wraps and still yields 4096 unique IDs
+func TestSequenceStartWrapKeepsUniqueness(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+
+	sf, err := snowflake.NewBuilder().
+		SetClock(clock).
+		SetSequenceStart(snowflake.SequenceStartRandom).
+		SetExhaustionPolicy(snowflake.ExhaustionError).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	ids, err := sf.GenerateN(4096)
+	if err != nil {
+		t.Fatalf("Failed to generate a full millisecond: %v", err)
+	}
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("Duplicate ID after wrap: %d", id)
+		}
+		seen[id] = true
+	}
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrOverFlow) {
+		t.Errorf("Expected ErrOverFlow once the sequence is back at its start, got %v", err)
+	}
+}
+
+// TestGenerateN tests batch generation and decoding of the results
+func TestGenerateN(t *testing.T) {
+	sf, err := snowflake.NewBuilder().
+		SetDatacenterID(3).
+		SetWorkerID(7).
+		SetSequenceStart(snowflake.SequenceStartRotating).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	before := time.Now().Add(-time.Millisecond)
+	ids, err := sf.GenerateN(10000)
+	if err != nil {
+		t.Fatalf("Failed to generate batch: %v", err)
+	}
+	after := time.Now().Add(time.Millisecond)
+	if len(ids) != 10000 {
+		t.Fatalf("Expected 10000 IDs, got %d", len(ids))
+	}
+
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("Duplicate ID in batch: %d", id)
+		}
+		seen[id] = true
+
+		c, err := sf.Decode(id)
+		if err != nil {
+			t.Fatalf("Failed to decode %d: %v", id, err)
+		}
+		if c.DatacenterID != 3 || c.WorkerID != 7 {
+			t.Errorf("Expected node 3/7, got %d/%d", c.DatacenterID, c.WorkerID)
+		}
+		if c.Time.Before(before) || c.Time.After(after) {
+			t.Errorf("Decoded time %v outside [%v, %v]", c.Time, before, after)
+		}
+	}
+}
+
+// TestDecode tests decoding a hand-built ID
+func TestDecode(t *testing.T) {
+	id := int64(123456)<<22 | int64(5)<<17 | int64(9)<<12 | 42
+	c, err := snowflake.Decode(id)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if c.Timestamp != snowflake.Epoch+123456 || c.DatacenterID != 5 || c.WorkerID != 9 || c.Sequence != 42 {
+		t.Errorf("Unexpected components: %+v", c)
+	}
+	if c.Time.UnixNano()/int64(time.Millisecond) != c.Timestamp {
+		t.Errorf("Time %v does not match timestamp %d", c.Time, c.Timestamp)
+	}
+
+	if _, err := snowflake.Decode(-1); !errors.Is(err, snowflake.ErrInvalidID) {
+		t.Errorf("Expected ErrInvalidID, got %v", err)
+	}
+}
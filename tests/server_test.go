@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/server"
+	"github.com/sunquakes/snowredis/snowflake"
+	"github.com/sunquakes/snowredis/tests/mock"
+)
+
+// serve performs a GET request against the handler
+func serve(t *testing.T, handler http.Handler, target, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestHTTPHandlerEndpoints tests the JSON and text forms of every endpoint
+func TestHTTPHandlerEndpoints(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLeaseTTL(time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+	defer sf.Cleanup()
+	handler := server.NewHTTPHandler(sf)
+
+	rec := serve(t, handler, "/id", "")
+	var single map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &single); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected /id response %d %q: %v", rec.Code, rec.Body.String(), err)
+	}
+	id, err := strconv.ParseInt(single["id"], 10, 64)
+	if err != nil {
+		t.Fatalf("ID %q is not a decimal string: %v", single["id"], err)
+	}
+
+	rec = serve(t, handler, "/id?format=text", "")
+	if _, err := strconv.ParseInt(strings.TrimSpace(rec.Body.String()), 10, 64); err != nil {
+		t.Errorf("Text /id response %q is not an ID", rec.Body.String())
+	}
+
+	rec = serve(t, handler, "/ids?n=5", "")
+	var batch map[string][]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &batch); err != nil || len(batch["ids"]) != 5 {
+		t.Errorf("Expected 5 IDs from /ids, got %q: %v", rec.Body.String(), err)
+	}
+
+	rec = serve(t, handler, "/ids?n=3", "text/plain")
+	if lines := strings.Fields(rec.Body.String()); len(lines) != 3 {
+		t.Errorf("Expected 3 lines from text /ids, got %q", rec.Body.String())
+	}
+
+	for _, target := range []string{"/ids", "/ids?n=0", "/ids?n=abc", "/ids?n=" + strconv.Itoa(server.MaxBatchSize+1)} {
+		if rec := serve(t, handler, target, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, rec.Code)
+		}
+	}
+
+	rec = serve(t, handler, "/decode?id="+single["id"], "")
+	var decoded struct {
+		ID           string `json:"id"`
+		DatacenterID int64  `json:"datacenter_id"`
+		WorkerID     int64  `json:"worker_id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("Unexpected /decode response %q: %v", rec.Body.String(), err)
+	}
+	c, _ := sf.Decode(id)
+	if decoded.DatacenterID != c.DatacenterID || decoded.WorkerID != c.WorkerID {
+		t.Errorf("Decoded node %d/%d, expected %d/%d", decoded.DatacenterID, decoded.WorkerID, c.DatacenterID, c.WorkerID)
+	}
+	if rec := serve(t, handler, "/decode?id=x", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid id, got %d", rec.Code)
+	}
+
+	if rec := serve(t, handler, "/health?format=text", ""); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "ok" {
+		t.Errorf("Unexpected /health response %d %q", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/id", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+// fakeGenerator Counts IDs up from 1 and reports a configurable health error
+type fakeGenerator struct {
+	mu     sync.Mutex
+	next   int64
+	health error
+}
+
+func (g *fakeGenerator) GenerateContext(ctx context.Context) (int64, error) {
+	ids, err := g.GenerateNContext(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (g *fakeGenerator) GenerateNContext(_ context.Context, n int) ([]int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ids := make([]int64, n)
+	for i := range ids {
+		g.next++
+		ids[i] = g.next
+	}
+	return ids, nil
+}
+
+func (g *fakeGenerator) Decode(id int64) (snowflake.Components, error) {
+	return snowflake.DefaultLayout.Decode(id)
+}
+
+func (g *fakeGenerator) Health(context.Context) error {
+	return g.health
+}
+
+// TestHTTPHandlerFakeGenerator tests serving a generator other than RedisSnowflake
+func TestHTTPHandlerFakeGenerator(t *testing.T) {
+	gen := &fakeGenerator{health: errors.New("draining")}
+	handler := server.NewHTTPHandler(gen)
+
+	if rec := serve(t, handler, "/id?format=text", ""); strings.TrimSpace(rec.Body.String()) != "1" {
+		t.Errorf("Expected ID 1 from the fake generator, got %q", rec.Body.String())
+	}
+	if rec := serve(t, handler, "/ids?n=2", "text/plain"); strings.Join(strings.Fields(rec.Body.String()), ",") != "2,3" {
+		t.Errorf("Expected IDs 2 and 3, got %q", rec.Body.String())
+	}
+	if rec := serve(t, handler, "/health", ""); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "draining") {
+		t.Errorf("Expected 503 with the health error, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := serve(t, handler, "/metrics", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected no /metrics for a generator without Stats, got %d", rec.Code)
+	}
+}
+
+// TestHTTPHandlerCanceledRequest tests that generation stops once the client has gone away
+func TestHTTPHandlerCanceledRequest(t *testing.T) {
+	sf, err := snowflake.NewBuilder().SetRedisClient(mock.NewMockRedisClient()).
+		SetDatacenterID(1).SetWorkerID(1).SetStrictMode(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize in strict mode: %v", err)
+	}
+	defer sf.Cleanup()
+	handler := server.NewHTTPHandler(sf)
+
+	for _, target := range []string{"/id", "/ids?n=3"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), context.Canceled.Error()) {
+			t.Errorf("%s: expected 503 with the context error, got %d %q", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestLeaseReleasedOnCleanup tests that a leased slot is held while running and freed by Cleanup
+func TestLeaseReleasedOnCleanup(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	ctx := context.Background()
+
+	sf, err := snowflake.NewBuilder().
+		SetRedisClient(client).
+		SetDatacenterID(2).
+		SetWorkerID(3).
+		SetLeaseTTL(time.Minute).
+		SetLeaseHolder("test-holder").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with lease: %v", err)
+	}
+
+	value, err := client.Get(ctx, "snowflake:slot:2:3")
+	if err != nil {
+		t.Fatalf("Slot key not written: %v", err)
+	}
+	var info snowflake.LeaseInfo
+	if err := json.Unmarshal([]byte(value), &info); err != nil || info.Holder != "test-holder" {
+		t.Errorf("Unexpected lease value %q: %v", value, err)
+	}
+	if ttl, err := client.TTL(ctx, "snowflake:slot:2:3"); err != nil || ttl <= 0 {
+		t.Errorf("Expected the slot key to expire, got TTL %v: %v", ttl, err)
+	}
+
+	// A second node cannot take the same slot
+	_, err = snowflake.NewBuilder().
+		SetRedisClient(client).
+		SetDatacenterID(2).
+		SetWorkerID(3).
+		SetLeaseTTL(time.Minute).
+		Build()
+	if !errors.Is(err, snowflake.ErrSlotTaken) {
+		t.Errorf("Expected ErrSlotTaken, got %v", err)
+	}
+
+	sf.Cleanup()
+	if _, err := client.Get(ctx, "snowflake:slot:2:3"); err == nil {
+		t.Error("Slot key should be deleted by Cleanup")
+	}
+}
+
+// TestLeasedAllocationSkipsHeldSlots tests that auto-allocation picks distinct free slots
+func TestLeasedAllocationSkipsHeldSlots(t *testing.T) {
+	client := mock.NewMockRedisClient()
+	layout := snowflake.DefaultLayout
+	layout.DatacenterBits = 1
+	layout.WorkerBits = 1
+
+	seen := make(map[[2]int64]bool)
+	for i := 0; i < 4; i++ {
+		sf, err := snowflake.NewBuilder().SetRedisClient(client).SetLayout(layout).SetLeaseTTL(time.Minute).Build()
+		if err != nil {
+			t.Fatalf("Failed to lease slot %d: %v", i, err)
+		}
+		defer sf.Cleanup()
+		id, _ := sf.Generate()
+		c, _ := sf.Decode(id)
+		node := [2]int64{c.DatacenterID, c.WorkerID}
+		if seen[node] {
+			t.Errorf("Slot %v leased twice", node)
+		}
+		seen[node] = true
+	}
+
+	_, err := snowflake.NewBuilder().SetRedisClient(client).SetLayout(layout).SetLeaseTTL(time.Minute).Build()
+	if !errors.Is(err, snowflake.ErrNoFreeSlot) {
+		t.Errorf("Expected ErrNoFreeSlot, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// TestUUIDv7Format tests the version, variant, timestamp and canonical form of generated UUIDs
+func TestUUIDv7Format(t *testing.T) {
+	now := epochTime.Add(time.Hour)
+	clock := mock.NewMockClock(now)
+	sf, err := snowflake.NewBuilder().SetClock(clock).SetDatacenterID(3).SetWorkerID(17).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	gen := snowflake.NewUUIDv7Generator(sf)
+	u, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate UUID: %v", err)
+	}
+	s := u.String()
+	if len(s) != 36 || s[14] != '7' || !strings.ContainsRune("89ab", rune(s[19])) {
+		t.Errorf("Unexpected UUIDv7 form %s", s)
+	}
+	parsed, err := snowflake.ParseUUID(strings.ToUpper(s))
+	if err != nil || parsed != u {
+		t.Errorf("ParseUUID(%s): got %s, %v", s, parsed, err)
+	}
+
+	c, err := gen.Decode(u)
+	if err != nil {
+		t.Fatalf("Failed to decode UUID: %v", err)
+	}
+	if c.Timestamp != now.UnixMilli() || c.DatacenterID != 3 || c.WorkerID != 17 || c.Sequence != 0 {
+		t.Errorf("Unexpected components %+v", c)
+	}
+}
+
+// TestULIDFormat tests the Crockford form and decoding of generated ULIDs
+func TestULIDFormat(t *testing.T) {
+	now := epochTime.Add(time.Hour)
+	clock := mock.NewMockClock(now)
+	sf, err := snowflake.NewBuilder().SetClock(clock).SetDatacenterID(31).SetWorkerID(1).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	gen := snowflake.NewULIDGenerator(sf)
+	u, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ULID: %v", err)
+	}
+	s := u.String()
+	if len(s) != 26 {
+		t.Errorf("Unexpected ULID form %s", s)
+	}
+	parsed, err := snowflake.ParseULID(strings.ToLower(s))
+	if err != nil || parsed != u {
+		t.Errorf("ParseULID(%s): got %s, %v", s, parsed, err)
+	}
+
+	c := gen.Decode(u)
+	if c.Timestamp != now.UnixMilli() || c.DatacenterID != 31 || c.WorkerID != 1 || c.Sequence != 0 {
+		t.Errorf("Unexpected components %+v", c)
+	}
+}
+
+// TestWideIDsMonotonic tests that the counter orders IDs within a millisecond and across milliseconds
+func TestWideIDsMonotonic(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+	sf, err := snowflake.NewBuilder().SetClock(clock).SetSequenceStart(snowflake.SequenceStartRandom).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	uuidGen := snowflake.NewUUIDv7Generator(sf)
+	ulidGen := snowflake.NewULIDGenerator(sf)
+	var lastUUID snowflake.UUID
+	var lastULID string
+	for round := 0; round < 3; round++ {
+		uuids, err := uuidGen.GenerateN(100)
+		if err != nil {
+			t.Fatalf("Failed to generate UUIDs: %v", err)
+		}
+		ulids, err := ulidGen.GenerateN(100)
+		if err != nil {
+			t.Fatalf("Failed to generate ULIDs: %v", err)
+		}
+		for i, u := range uuids {
+			if bytes.Compare(u[:], lastUUID[:]) <= 0 {
+				t.Fatalf("UUID %s not greater than %s", u, lastUUID)
+			}
+			if c, _ := uuidGen.Decode(u); c.Sequence != int64(i) {
+				t.Fatalf("Expected counter %d, got %d", i, c.Sequence)
+			}
+			lastUUID = u
+		}
+		for i, u := range ulids {
+			if s := u.String(); s <= lastULID {
+				t.Fatalf("ULID %s not greater than %s", s, lastULID)
+			}
+			if c := ulidGen.Decode(u); c.Sequence != int64(100+i) {
+				t.Fatalf("Expected counter %d, got %d", 100+i, c.Sequence)
+			}
+			lastULID = u.String()
+		}
+		clock.Advance(time.Millisecond)
+	}
+	if got := sf.Stats().Generated; got != 600 {
+		t.Errorf("Expected 600 generated IDs, got %d", got)
+	}
+}
+
+// TestWideIDsClockRollback tests that 128-bit IDs share the rollback protection of Generate
+func TestWideIDsClockRollback(t *testing.T) {
+	clock := mock.NewMockClock(epochTime.Add(time.Hour))
+	sf, err := snowflake.NewBuilder().SetClock(clock).Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer sf.Cleanup()
+
+	if _, err := sf.Generate(); err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	clock.Advance(-5 * time.Millisecond)
+	if _, err := snowflake.NewUUIDv7Generator(sf).Generate(); !errors.Is(err, snowflake.ErrClockRollback) {
+		t.Errorf("Expected ErrClockRollback, got %v", err)
+	}
+	if _, err := snowflake.NewULIDGenerator(sf).Generate(); !errors.Is(err, snowflake.ErrClockRollback) {
+		t.Errorf("Expected ErrClockRollback, got %v", err)
+	}
+
+	sf.Cleanup()
+	if _, err := snowflake.NewULIDGenerator(sf).Generate(); !errors.Is(err, snowflake.ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+// TestParseWideIDsInvalid tests the rejection of malformed UUIDs and ULIDs
+func TestParseWideIDsInvalid(t *testing.T) {
+	for _, s := range []string{"", "0190a6b2-7c3d-7e8f-9a0b", "0190a6b27c3d7e8f9a0b1c2d3e4f5a6b", "0190a6b2-7c3d-7e8f-9a0b-1c2d3e4f5a6g"} {
+		if _, err := snowflake.ParseUUID(s); !errors.Is(err, snowflake.ErrInvalidID) {
+			t.Errorf("ParseUUID(%q): expected ErrInvalidID, got %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "01ARZ3NDEKTSV4RRFFQ69G5FA", "81ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAU"} {
+		if _, err := snowflake.ParseULID(s); !errors.Is(err, snowflake.ErrInvalidID) {
+			t.Errorf("ParseULID(%q): expected ErrInvalidID, got %v", s, err)
+		}
+	}
+	// A version 4 UUID is not decodable
+	v4, _ := snowflake.ParseUUID("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	if _, err := snowflake.DefaultLayout.DecodeUUID(v4); !errors.Is(err, snowflake.ErrInvalidID) {
+		t.Errorf("Expected ErrInvalidID for a version 4 UUID, got %v", err)
+	}
+}
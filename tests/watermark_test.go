@@ -0,0 +1,217 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sunquakes/snowredis/tests/mock"
+
+	"github.com/sunquakes/snowredis/snowflake"
+)
+
+// TestWatermarkRefusesStartBehindMark tests that Build refuses to start when the clock is far behind the stored mark
+func TestWatermarkRefusesStartBehindMark(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+	store, err := snowflake.NewRedisWatermarkStore(mockRedis)
+	if err != nil {
+		t.Fatalf("Failed to create watermark store: %v", err)
+	}
+
+	// Simulate a previous run whose clock was ten minutes ahead
+	future := time.Now().Add(10*time.Minute).UnixNano() / int64(time.Millisecond)
+	if err := store.Save(context.Background(), 1, 1, future); err != nil {
+		t.Fatalf("Failed to save watermark: %v", err)
+	}
+
+	_, err = snowflake.NewBuilder().
+		SetRedisClient(mockRedis).
+		SetDatacenterID(1).
+		SetWorkerID(1).
+		SetWatermarkStore(store).
+		Build()
+	if !errors.Is(err, snowflake.ErrClockBehindWatermark) {
+		t.Errorf("Expected ErrClockBehindWatermark, got %v", err)
+	}
+}
+
+// TestWatermarkWaitsForClock tests that Build waits for the clock to pass a mark slightly in the future
+func TestWatermarkWaitsForClock(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+	store, err := snowflake.NewRedisWatermarkStore(mockRedis)
+	if err != nil {
+		t.Fatalf("Failed to create watermark store: %v", err)
+	}
+
+	mark := time.Now().Add(50*time.Millisecond).UnixNano() / int64(time.Millisecond)
+	if err := store.Save(context.Background(), 1, 1, mark); err != nil {
+		t.Fatalf("Failed to save watermark: %v", err)
+	}
+
+	sf, err := snowflake.NewBuilder().
+		SetRedisClient(mockRedis).
+		SetDatacenterID(1).
+		SetWorkerID(1).
+		SetWatermarkStore(store).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize Redis Snowflake: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	if timestamp := (id >> 22) + snowflake.Epoch; timestamp <= mark {
+		t.Errorf("ID timestamp %d should be after the stored mark %d", timestamp, mark)
+	}
+
+	// The running instance reserves a mark ahead of the clock
+	stored, err := store.Load(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to load watermark: %v", err)
+	}
+	if stored <= mark {
+		t.Errorf("Expected stored mark to advance past %d, got %d", mark, stored)
+	}
+}
+
+// TestWatermarkFrozenClock tests that Build gives up when the clock does not advance past a close mark
+func TestWatermarkFrozenClock(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+	store, err := snowflake.NewRedisWatermarkStore(mockRedis)
+	if err != nil {
+		t.Fatalf("Failed to create watermark store: %v", err)
+	}
+	now := epochTime.Add(time.Hour)
+	if err := store.Save(context.Background(), 1, 1, now.Add(10*time.Millisecond).UnixMilli()); err != nil {
+		t.Fatalf("Failed to save watermark: %v", err)
+	}
+
+	_, err = snowflake.NewBuilder().
+		SetRedisClient(mockRedis).
+		SetDatacenterID(1).
+		SetWorkerID(1).
+		SetClock(mock.NewMockClock(now)).
+		SetWatermarkStore(store).
+		SetWatermarkMaxWait(50 * time.Millisecond).
+		Build()
+	if !errors.Is(err, snowflake.ErrClockBehindWatermark) {
+		t.Errorf("Expected ErrClockBehindWatermark, got %v", err)
+	}
+}
+
+// TestWatermarkInvalidInterval tests that Build rejects a non-positive write interval
+func TestWatermarkInvalidInterval(t *testing.T) {
+	mockRedis := mock.NewMockRedisClient()
+	store, err := snowflake.NewRedisWatermarkStore(mockRedis)
+	if err != nil {
+		t.Fatalf("Failed to create watermark store: %v", err)
+	}
+	for _, interval := range []time.Duration{0, -time.Second} {
+		_, err := snowflake.NewBuilder().
+			SetRedisClient(mockRedis).
+			SetDatacenterID(1).
+			SetWorkerID(1).
+			SetWatermarkStore(store).
+			SetWatermarkInterval(interval).
+			Build()
+		if !errors.Is(err, snowflake.ErrInvalidInterval) {
+			t.Errorf("Interval %v: expected ErrInvalidInterval, got %v", interval, err)
+		}
+	}
+}
+
+// TestFileWatermarkStore tests that a graceful shutdown records the exact last-issued timestamp
+func TestFileWatermarkStore(t *testing.T) {
+	store := snowflake.FileWatermarkStore{Dir: t.TempDir()}
+
+	sf, err := snowflake.NewBuilder().
+		SetDatacenterID(2).
+		SetWorkerID(3).
+		SetWatermarkStore(store).
+		SetWatermarkInterval(10 * time.Millisecond).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize with file watermark: %v", err)
+	}
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	sf.Cleanup()
+
+	stored, err := store.Load(context.Background(), 2, 3)
+	if err != nil {
+		t.Fatalf("Failed to load watermark: %v", err)
+	}
+	if want := (id >> 22) + snowflake.Epoch; stored != want {
+		t.Errorf("Expected stored mark %d, got %d", want, stored)
+	}
+
+	// A restart right after a graceful shutdown only waits for the next millisecond
+	restarted, err := snowflake.NewBuilder().
+		SetDatacenterID(2).
+		SetWorkerID(3).
+		SetWatermarkStore(store).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to restart with file watermark: %v", err)
+	}
+	defer restarted.Cleanup()
+
+	next, err := restarted.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID after restart: %v", err)
+	}
+	if next <= id {
+		t.Errorf("ID after restart %d should be greater than %d", next, id)
+	}
+}
+
+// TestWatermarkStrictMode tests that strict-mode IDs advance the high-water mark and are guarded by it
+func TestWatermarkStrictMode(t *testing.T) {
+	store := snowflake.FileWatermarkStore{Dir: t.TempDir()}
+	clock := mock.NewMockClock(time.Now())
+
+	sf, err := snowflake.NewBuilder().
+		SetRedisClient(mock.NewMockRedisClient()).
+		SetDatacenterID(2).
+		SetWorkerID(3).
+		SetStrictMode(true).
+		SetClock(clock).
+		SetWatermarkStore(store).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to initialize in strict mode with watermark: %v", err)
+	}
+	defer sf.Cleanup()
+
+	id, err := sf.Generate()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	issued := (id >> 22) + snowflake.Epoch
+
+	// A clock set back below the issued ID must not issue IDs for that millisecond again
+	clock.Advance(-5 * time.Millisecond)
+	if _, err := sf.Generate(); !errors.Is(err, snowflake.ErrClockRollback) {
+		t.Errorf("Expected ErrClockRollback behind the issued ID, got %v", err)
+	}
+	clock.Advance(10 * time.Millisecond)
+	if _, err := sf.Generate(); err != nil {
+		t.Fatalf("Failed to generate ID once the clock passed the mark: %v", err)
+	}
+	sf.Cleanup()
+
+	stored, err := store.Load(context.Background(), 2, 3)
+	if err != nil {
+		t.Fatalf("Failed to load watermark: %v", err)
+	}
+	if stored <= issued {
+		t.Errorf("Expected stored mark past the first strict-mode ID at %d, got %d", issued, stored)
+	}
+}